@@ -2,99 +2,690 @@ package main
 
 import (
 	"bufio"
+	"context"
 	"errors"
 	"fmt"
+	"github.com/gorilla/websocket"
 	"github.com/manifoldco/promptui"
 	"github.com/spf13/cobra"
+	"io"
+	"mars-rover/internal/api"
 	"mars-rover/internal/app"
+	"mars-rover/internal/fleet"
+	"mars-rover/internal/mission"
+	"mars-rover/internal/models"
 	"mars-rover/internal/optimization"
+	"mars-rover/internal/planner"
+	"mars-rover/internal/plateau"
+	"mars-rover/internal/routefile"
 	"mars-rover/internal/rover"
+	"mars-rover/internal/server"
 	"os"
+	"os/signal"
+	"strconv"
 	"strings"
+	"syscall"
+	"text/tabwriter"
+	"time"
 )
 
 const (
 	ModeInteractive = "interactive"
 	ModeConsole     = "console"
 	ModeFile        = "file"
+	ModeFleet       = "fleet"
+)
+
+// maxTraceCells сколько клеток одного движения выводится в трассировке в консоли
+const maxTraceCells = 20
+
+// maxTraceSource сколько исходных команд одного движения выводится в трассировке
+const maxTraceSource = 20
+
+const (
+	InputRoute   = "route"
+	InputClassic = "classic"
+	InputStream  = "stream"
 )
 
 func main() {
 	var (
-		mode     string
-		filePath string
+		mode        string
+		filePath    string
+		width       int
+		height      int
+		edge        string
+		obstacles   string
+		safe        bool
+		interleaved bool
+		inputFormat string
+		startX      int
+		startY      int
+		startDir    string
+		tracePath   string
+		stats       bool
+		battery     int
+		energy      string
+		strategy    string
+		remote      string
+		// outputFormat формат результата: text, json, yaml или csv
+		outputFormat string
+		// status код завершения rover
+		status int
 	)
 
 	var rootCmd = &cobra.Command{
 		Use:   "rover",
 		Short: "Марсоход",
 		Run: func(cmd *cobra.Command, args []string) {
-			fmt.Println("Добро пожаловать в центр управления марсоходом 'Curiosity'!")
+			var err error
+			outputFormat, err = ParseOutput(outputFormat)
+			if err != nil {
+				fmt.Println(err)
+				status = ExitFailure
+				return
+			}
+			if outputFormat != OutputText {
+				console = os.Stderr
+			}
+			// emit выводит результат в формате json, yaml или csv
+			emit := func(result Result) {
+				if err := WriteResult(os.Stdout, outputFormat, result); err != nil {
+					fmt.Fprintf(os.Stderr, "Ошибка вывода результата: %v\n", err)
+				}
+			}
+			// fail сообщает об ошибке, после которой марсоход не двигался: в формате text выводит message,
+			// в остальных - результат с ошибкой code
+			fail := func(exit int, code, message string) {
+				status = exit
+				if outputFormat == OutputText {
+					fmt.Println(message)
+					return
+				}
+				emit(FailureResult(code, message))
+			}
+			fmt.Fprintln(console, "Добро пожаловать в центр управления марсоходом 'Curiosity'!")
 
+			if mode == "" && outputFormat != OutputText {
+				fail(ExitFailure, api.CodeInvalidRequest,
+					fmt.Sprintf("Для формата вывода %s укажите режим флагом --mode", outputFormat))
+				return
+			}
 			if mode == "" {
-				var err error
 				mode, err = SelectMode()
 				if err != nil {
-					fmt.Printf("Ошибка выбора: %v\n", err)
+					fail(ExitFailure, api.CodeInvalidRequest, fmt.Sprintf("Ошибка выбора: %v", err))
 					return
 				}
 			}
+			if mode == ModeInteractive && outputFormat != OutputText {
+				fail(ExitFailure, api.CodeInvalidRequest, "Интерактивный режим поддерживает только --output=text")
+				return
+			}
+
+			dir, err := models.ParseDirection(startDir)
+			if err != nil {
+				fail(ExitFailure, api.CodeInvalidStart, fmt.Sprintf("Некорректное начальное направление: %v", err))
+				return
+			}
+			start := routefile.Start{Pos: models.Coordinates{X: startX, Y: startY}, Direction: dir}
 
-			r := rover.NewRover()
-			optimizer := optimization.NewOptimizer()
-			a := app.NewApp(r, optimizer)
+			var route *routefile.File
+			if mode == ModeFile && inputFormat != InputClassic && inputFormat != InputStream {
+				route, err = GetRouteFromFile(filePath)
+				// ошибки маршрута из файла сообщаются так же, как из консоли
+				if ExitCode(err) == ExitInvalidRoute {
+					status = ExitInvalidRoute
+					if outputFormat == OutputText {
+						fmt.Println(app.HandleError(err))
+					} else {
+						emit(NewResult(start.Pos, start.Direction, nil, nil, err))
+					}
+					return
+				}
+				if err != nil {
+					fail(ExitFailure, api.CodeInvalidRequest, fmt.Sprintf("Ошибка получения команд: %v", err))
+					return
+				}
+				// флаги переопределяют только свои поля заголовка, остальные берутся из файла
+				if route.Start != nil {
+					if !cmd.Flags().Changed("x") {
+						start.Pos.X = route.Start.Pos.X
+					}
+					if !cmd.Flags().Changed("y") {
+						start.Pos.Y = route.Start.Pos.Y
+					}
+					if !cmd.Flags().Changed("dir") {
+						start.Direction = route.Start.Direction
+					}
+				}
+			}
+
+			roverOpts := []rover.Option{rover.WithPosition(start.Pos), rover.WithDirection(start.Direction)}
+			p, err := NewPlateau(width, height, edge, obstacles)
+			if err != nil {
+				fail(ExitFailure, api.CodeInvalidRequest, fmt.Sprintf("Ошибка настройки плато: %v", err))
+				return
+			}
+			// source команды маршрута и диапазоны, из которых получены движения, для трассировки
+			source := &TraceSource{}
+			if cmd.Flags().Changed("battery") {
+				costs, err := ParseCosts(energy)
+				if err != nil {
+					fail(ExitFailure, api.CodeInvalidRequest, fmt.Sprintf("Некорректная модель энергии: %v", err))
+					return
+				}
+				roverOpts = append(roverOpts, rover.WithBattery(costs, battery))
+			}
+			if tracePath != "" && (mode == ModeConsole || mode == ModeFile && inputFormat != InputClassic && inputFormat != InputStream) {
+				trace := rover.NewTrace()
+				roverOpts = append(roverOpts, rover.WithRecorder(trace))
+				defer func() {
+					if err := WriteTrace(tracePath, trace, p, source); err != nil {
+						fmt.Fprintf(console, "Ошибка записи трассировки: %v\n", err)
+					}
+				}()
+			}
+			r := rover.NewRover(roverOpts...)
+			var opts []app.Option
+			if p != nil {
+				if !p.Contains(r.Pos) || p.Blocked(r.Pos) {
+					fail(ExitFailure, api.CodeInvalidStart, fmt.Sprintf(
+						"Начальная позиция (%d, %d) находится за пределами плато или занята препятствием", r.Pos.X, r.Pos.Y))
+					return
+				}
+				opts = append(opts, app.WithPlateau(p))
+			}
+			if safe && cmd.Flags().Changed("optimize") {
+				fail(ExitFailure, api.CodeInvalidRequest, "Флаг --safe выбирает стратегию obstacle-aware, "+
+					"его нельзя указывать вместе с --optimize")
+				return
+			}
+			if safe {
+				strategy = "obstacle-aware"
+			}
+			optimizer, err := optimization.New(strategy,
+				optimization.WithPlateau(p), optimization.WithStart(start.Pos, start.Direction))
+			if err != nil {
+				fail(ExitFailure, api.CodeUnknownOptimizer, fmt.Sprintf("Некорректная стратегия оптимизации: %v", err))
+				return
+			}
+			// reporter оптимизатор, который сообщает, какие отрезки маршрута он схлопнул
+			reporter, _ := optimizer.(interface{ LastReport() optimization.Report })
+			a := app.NewApp(r, optimizer, opts...)
+
+			// finish выводит результат маршрута commands в формате --output и запоминает код завершения
+			finish := func(commands string, position models.Coordinates, direction models.Direction, err error) {
+				source.Commands = commands
+				status = ExitCode(err)
+				if outputFormat != OutputText {
+					result := NewResult(position, direction, a.Route(), a.Spans(), err)
+					if r.Battery != nil {
+						charge := r.Battery.Charge
+						result.Battery = &charge
+					}
+					emit(result)
+				}
+				if err != nil {
+					if outputFormat == OutputText {
+						fmt.Println(app.HandleError(err))
+					}
+					return
+				}
+
+				if outputFormat == OutputText {
+					fmt.Printf("Расчёт выполнен успешно. Конечное положение Марсохода: (%d, %d), направление: %s\n",
+						position.X, position.Y, direction)
+				}
+				// в потоковом режиме commands пустые: маршрут не хранится, статистику и отчёт оптимизации считать не по чему
+				if stats && commands != "" {
+					PrintStats(commands, a.Route(), r.Odometer)
+				}
+				if r.Battery != nil && outputFormat == OutputText {
+					fmt.Printf("Оставшийся заряд батареи: %d\n", r.Battery.Charge)
+				}
+				if reporter != nil && commands != "" {
+					PrintMergeReport(reporter.LastReport())
+				}
+			}
 
 			switch mode {
 			case ModeInteractive:
-				fmt.Println("Используйте стрелки для управления марсоходом. Нажмите Ctrl+C для выхода.")
-				err := HandleInteractiveMode(a)
+				fmt.Println("Используйте стрелки для управления марсоходом. u или Ctrl+Z отменяет команду, r или Ctrl+Y " +
+					"повторяет её, c ставит контрольную точку, цифра возвращает к точке с этим номером. Нажмите Ctrl+C для выхода.")
+				var err error
+				if remote != "" {
+					err = HandleRemoteInteractiveMode(a, remote)
+				} else {
+					err = HandleInteractiveMode(a)
+				}
 				if err != nil {
 					fmt.Printf("Ошибка в интерактивном режиме: %v\n", err)
+					status = ExitFailure
 				}
 			case ModeConsole:
 				commands, err := GetCommandsFromConsole()
 				if err != nil {
-					fmt.Printf("Ошибка получения команд: %v\n", err)
+					fail(ExitFailure, api.CodeInvalidRequest, fmt.Sprintf("Ошибка получения команд: %v", err))
 					return
 				}
 				position, direction, err := a.HandleCommands(commands)
-				if err != nil {
-					fmt.Println(app.HandleError(err))
+				finish(commands, position, direction, err)
+			case ModeFile:
+				if inputFormat == InputClassic {
+					results, exit, err := HandleClassicFile(filePath, edge, strategy, p)
+					if err != nil {
+						fail(exit, api.CodeInvalidRequest, fmt.Sprintf("Ошибка получения команд: %v", err))
+						return
+					}
+					status = exit
+					PrintResults(outputFormat, results, PrintClassicResults)
 					return
 				}
-				fmt.Printf("Расчёт выполнен успешно. Конечное положение Марсохода: (%d, %d), направление: %s\n",
-					position.X, position.Y, direction)
-			case ModeFile:
-				commands, err := GetCommandsFromFile(filePath)
-				if err != nil {
-					fmt.Printf("Ошибка получения команд: %v\n", err)
+				if inputFormat == InputStream {
+					file, err := os.Open(filePath)
+					if err != nil {
+						fail(ExitFailure, api.CodeInvalidRequest, fmt.Sprintf("Ошибка получения команд: %v", err))
+						return
+					}
+					defer file.Close()
+					position, direction, err := a.CalculateStream(file)
+					finish("", position, direction, err)
 					return
 				}
-				position, direction, err := a.HandleCommands(commands)
+				position, direction, err := a.HandleCommands(route.Commands)
+				// смещение в строке команд с подставленными макросами переводится в место в файле
+				var routeErr *models.RouteError
+				if errors.As(err, &routeErr) && routeErr.Index >= 0 {
+					routeErr.Location = route.Locate(routeErr.Index).String()
+				}
+				finish(route.Commands, position, direction, err)
+			case ModeFleet:
+				results, exit, err := HandleFleetMode(filePath, p, optimizer, interleaved)
 				if err != nil {
-					fmt.Println(app.HandleError(err))
+					fail(exit, api.CodeInvalidRequest, fmt.Sprintf("Ошибка запуска группы марсоходов: %v", err))
 					return
 				}
-				fmt.Printf("Расчёт выполнен успешно. Конечное положение Марсохода: (%d, %d), направление: %s\n",
-					position.X, position.Y, direction)
+				status = exit
+				PrintResults(outputFormat, results, PrintFleetResults)
 			default:
-				fmt.Println("Неизвестный режим")
+				fail(ExitFailure, api.CodeInvalidRequest, "Неизвестный режим")
 			}
 		},
 	}
 
-	rootCmd.Flags().StringVarP(&mode, "mode", "m", "", "Режим работы (console, file, interactive)")
+	rootCmd.Flags().StringVarP(&mode, "mode", "m", "", "Режим работы (console, file, interactive, fleet)")
 	rootCmd.Flags().StringVarP(&filePath, "file", "f", "", "Путь к файлу с командами")
+	rootCmd.PersistentFlags().IntVar(&width, "width", 0, "Ширина плато, 0 - без ограничений")
+	rootCmd.PersistentFlags().IntVar(&height, "height", 0, "Высота плато, 0 - без ограничений")
+	rootCmd.PersistentFlags().StringVar(&edge, "edge", string(plateau.EdgeReject), "Поведение на краю плато (reject, clamp, wrap)")
+	rootCmd.PersistentFlags().StringVar(&obstacles, "obstacles", "", "Путь к файлу с препятствиями")
+	rootCmd.Flags().BoolVar(&safe, "safe", false,
+		"Оптимизировать маршрут с учётом препятствий и вывести отчёт, то же что --optimize=obstacle-aware, "+
+			"не сочетается с --optimize")
+	rootCmd.Flags().StringVar(&strategy, "optimize", optimization.DefaultStrategy,
+		"Стратегия оптимизации маршрута, список стратегий выводит команда optimizers")
+	rootCmd.PersistentFlags().IntVar(&startX, "x", 1, "Начальная координата X марсохода")
+	rootCmd.PersistentFlags().IntVar(&startY, "y", 1, "Начальная координата Y марсохода")
+	rootCmd.PersistentFlags().StringVar(&startDir, "dir", string(models.North), "Начальное направление марсохода (N, S, E, W)")
+	rootCmd.Flags().StringVar(&inputFormat, "input-format", InputRoute,
+		"Формат файла с командами (route - строка команд, classic - плато и пары строк \"1 2 N\" / \"LMLMLMLMM\", "+
+			"stream - большой журнал команд без групп и макросов, читается потоком)")
+	rootCmd.Flags().StringVar(&tracePath, "trace", "",
+		"Записать трассировку маршрута в режимах console и file: - выводит её в консоль, иначе путь к файлу")
+	rootCmd.Flags().BoolVar(&stats, "stats", false,
+		"В режимах console и file вывести пробег марсохода и сколько клеток и поворотов сэкономил оптимизатор")
+	rootCmd.Flags().IntVar(&battery, "battery", 0,
+		"Начальный заряд батареи марсохода, без флага заряд не ограничен")
+	rootCmd.Flags().StringVar(&energy, "energy", "1,1,1,0",
+		"Стоимость клетки вперёд, клетки назад, поворота на 90 градусов и каждого действия через запятую")
+	rootCmd.Flags().StringVar(&remote, "remote", "",
+		"В режиме interactive управлять марсоходом сервера rover serve, например ws://localhost:8080/api/v1/control")
+	rootCmd.Flags().BoolVar(&interleaved, "interleaved", false, "В режиме fleet марсоходы ходят по очереди по одному шагу")
+	rootCmd.Flags().StringVarP(&outputFormat, "output", "o", OutputText,
+		"Формат результата (text, json, yaml, csv). В json, yaml и csv в stdout выводится только результат, "+
+			"приглашения и отчёты уходят в stderr")
+
+	planCmd := &cobra.Command{
+		Use:   "plan X Y [направление]",
+		Short: "Рассчитать кратчайший маршрут до клетки",
+		Args:  cobra.RangeArgs(2, 3),
+		Run: func(cmd *cobra.Command, args []string) {
+			dir, err := models.ParseDirection(startDir)
+			if err != nil {
+				fmt.Printf("Некорректное начальное направление: %v\n", err)
+				status = ExitFailure
+				return
+			}
+			p, err := NewPlateau(width, height, edge, obstacles)
+			if err != nil {
+				fmt.Printf("Ошибка настройки плато: %v\n", err)
+				status = ExitFailure
+				return
+			}
+			start := planner.State{Pos: models.Coordinates{X: startX, Y: startY}, Direction: dir}
+			if err := HandlePlan(p, start, args); err != nil {
+				fmt.Printf("Ошибка планирования маршрута: %v\n", err)
+				status = ExitFailure
+			}
+		},
+	}
+	rootCmd.AddCommand(planCmd)
+
+	rootCmd.AddCommand(&cobra.Command{
+		Use:   "optimizers",
+		Short: "Показать стратегии оптимизации маршрута",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := PrintStrategies(os.Stdout, optimization.DefaultRegistry.Strategies()); err != nil {
+				fmt.Printf("Ошибка вывода стратегий: %v\n", err)
+				status = ExitFailure
+			}
+		},
+	})
+
+	var (
+		addr          string
+		serveStrategy string
+		idleTimeout   time.Duration
+	)
+	serveCmd := &cobra.Command{
+		Use:   "serve",
+		Short: "Запустить HTTP API для расчёта маршрутов",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			p, err := NewPlateau(width, height, edge, obstacles)
+			if err != nil {
+				fmt.Printf("Ошибка настройки плато: %v\n", err)
+				status = ExitFailure
+				return
+			}
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+
+			s := server.NewServer(server.WithPlateau(p), server.WithStrategy(serveStrategy),
+				server.WithIdleTimeout(idleTimeout))
+			fmt.Printf("HTTP API слушает %s, Ctrl+C для остановки\n", addr)
+			if err := s.ListenAndServe(ctx, addr); err != nil {
+				fmt.Printf("Ошибка HTTP API: %v\n", err)
+				status = ExitFailure
+				return
+			}
+			fmt.Println("HTTP API остановлен")
+		},
+	}
+	serveCmd.Flags().StringVar(&addr, "addr", ":8080", "Адрес, на котором HTTP API принимает запросы")
+	serveCmd.Flags().StringVar(&serveStrategy, "optimize", optimization.DefaultStrategy,
+		"Стратегия оптимизации для запросов, в которых она не указана")
+	serveCmd.Flags().DurationVar(&idleTimeout, "idle-timeout", server.DefaultIdleTimeout,
+		"Через сколько времени без запросов сессия марсохода удаляется")
+	rootCmd.AddCommand(serveCmd)
+
+	var (
+		grpcAddr     string
+		grpcStrategy string
+	)
+	grpcServeCmd := &cobra.Command{
+		Use:   "grpc-serve",
+		Short: "Запустить gRPC-сервис для расчёта маршрутов и управления марсоходом",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			p, err := NewPlateau(width, height, edge, obstacles)
+			if err != nil {
+				fmt.Printf("Ошибка настройки плато: %v\n", err)
+				status = ExitFailure
+				return
+			}
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+
+			s := server.NewGRPCServer(server.WithPlateau(p), server.WithStrategy(grpcStrategy))
+			fmt.Printf("gRPC-сервис слушает %s, Ctrl+C для остановки\n", grpcAddr)
+			if err := s.ListenAndServe(ctx, grpcAddr); err != nil {
+				fmt.Printf("Ошибка gRPC-сервиса: %v\n", err)
+				status = ExitFailure
+				return
+			}
+			fmt.Println("gRPC-сервис остановлен")
+		},
+	}
+	grpcServeCmd.Flags().StringVar(&grpcAddr, "addr", ":9090", "Адрес, на котором gRPC-сервис принимает запросы")
+	grpcServeCmd.Flags().StringVar(&grpcStrategy, "optimize", optimization.DefaultStrategy,
+		"Стратегия оптимизации для запросов, в которых она не указана")
+	rootCmd.AddCommand(grpcServeCmd)
 
 	if err := rootCmd.Execute(); err != nil {
 		fmt.Printf("Ошибка выполнения команды: %v\n", err)
+		status = ExitFailure
+	}
+	os.Exit(status)
+}
+
+// NewPlateau собирает плато из флагов командной строки, если плато не задано, возвращает nil
+func NewPlateau(width, height int, edge, obstaclesPath string) (*plateau.Plateau, error) {
+	if width == 0 && height == 0 && obstaclesPath == "" {
+		return nil, nil
+	}
+
+	mode, err := plateau.ParseEdgeMode(edge)
+	if err != nil {
+		return nil, err
+	}
+	p, err := plateau.NewPlateau(width, height, mode)
+	if err != nil {
+		return nil, err
 	}
+
+	if obstaclesPath != "" {
+		p.Obstacles, err = plateau.LoadObstacles(obstaclesPath)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка чтения препятствий: %w", err)
+		}
+	}
+	return p, nil
+}
+
+// HandlePlan рассчитывает кратчайший маршрут из start в клетку из аргументов "X Y [направление]" и выводит его
+func HandlePlan(p *plateau.Plateau, start planner.State, args []string) error {
+	x, errX := strconv.Atoi(args[0])
+	y, errY := strconv.Atoi(args[1])
+	if errX != nil || errY != nil {
+		return fmt.Errorf("координаты цели должны быть целыми числами, получено %q %q", args[0], args[1])
+	}
+	target := models.Coordinates{X: x, Y: y}
+	var heading models.Direction
+	if len(args) == 3 {
+		dir, err := models.ParseDirection(args[2])
+		if err != nil {
+			return err
+		}
+		heading = dir
+	}
+	if p != nil && (!p.Contains(start.Pos) || p.Blocked(start.Pos)) {
+		return fmt.Errorf("начальная позиция (%d, %d) находится за пределами плато или занята препятствием",
+			start.Pos.X, start.Pos.Y)
+	}
+
+	commands, err := planner.Plan(p, start, target, heading)
+	if err != nil {
+		return err
+	}
+	if commands == "" {
+		fmt.Printf("Марсоход уже находится в (%d, %d)\n", target.X, target.Y)
+		return nil
+	}
+	fmt.Printf("Маршрут до (%d, %d): %s\nКоманд: %d\n", target.X, target.Y, commands, len(commands))
+	return nil
+}
+
+// ParseCosts разбирает модель энергии "вперёд,назад,поворот,простой": 1,2,1,0
+func ParseCosts(value string) (rover.Costs, error) {
+	fields := strings.Split(value, ",")
+	if len(fields) != 4 {
+		return rover.Costs{}, fmt.Errorf("ожидалось 4 стоимости через запятую, получено %q", value)
+	}
+	costs := make([]int, len(fields))
+	for i, field := range fields {
+		cost, err := strconv.Atoi(strings.TrimSpace(field))
+		if err != nil || cost < 0 {
+			return rover.Costs{}, fmt.Errorf("стоимость должна быть неотрицательным целым числом, получено %q", field)
+		}
+		costs[i] = cost
+	}
+	return rover.Costs{Forward: costs[0], Backward: costs[1], Turn: costs[2], Idle: costs[3]}, nil
+}
+
+// PrintStrategies выводит таблицу стратегий оптимизации, стратегия по умолчанию отмечается звёздочкой
+func PrintStrategies(out io.Writer, strategies []optimization.Strategy) error {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "Стратегия\tПсевдонимы\tОписание")
+	for _, s := range strategies {
+		name := s.Name
+		if name == optimization.DefaultStrategy {
+			name += " *"
+		}
+		aliases := strings.Join(s.Aliases, ", ")
+		if aliases == "" {
+			aliases = "-"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", name, aliases, s.Description)
+	}
+	return w.Flush()
+}
+
+// PrintMergeReport выводит, какие отрезки маршрута схлопнул оптимизатор
+func PrintMergeReport(report optimization.Report) {
+	fmt.Fprintln(console, "Отчёт оптимизации:")
+	for _, merge := range report.Merges {
+		status := "схлопнут"
+		if !merge.Merged {
+			status = "не схлопнут, схлопнутое движение заехало бы в другие клетки"
+		}
+		moves := optimization.FormatMoves(merge.Moves)
+		if moves == "" {
+			moves = "-"
+		}
+		fmt.Fprintf(console, "  команды [%d, %d) %s => %s, %s\n", merge.Start, merge.End, merge.Commands, moves, status)
+	}
+}
+
+// TraceSource исходный маршрут, по которому трассировка показывает, из каких команд получено каждое движение
+type TraceSource struct {
+	Commands string
+}
+
+// fragment возвращает исходные команды span: [0, 5) FFFBB
+func (s *TraceSource) fragment(span models.Span) string {
+	if s == nil || span == (models.Span{}) || span.End > len(s.Commands) {
+		return "-"
+	}
+	commands := s.Commands[span.Start:span.End]
+	if len(commands) > maxTraceSource {
+		commands = commands[:maxTraceSource] + "..."
+	}
+	return fmt.Sprintf("[%d, %d) %s", span.Start, span.End, commands)
+}
+
+// PrintStats выводит пробег марсохода и сравнение исходного маршрута commands с оптимизированным route
+func PrintStats(commands string, route []models.Move, odometer rover.Odometer) {
+	stats, err := optimization.NewStats(commands, route)
+	if err != nil {
+		fmt.Fprintf(console, "Ошибка расчёта статистики: %v\n", err)
+		return
+	}
+	fmt.Fprintln(console, "Статистика маршрута:")
+	fmt.Fprintf(console, "  команд: %d, движений после оптимизации: %d\n", stats.Commands, stats.Moves)
+	fmt.Fprintf(console, "  пробег: вперёд %d, назад %d, поворотов %d\n", odometer.Forward, odometer.Backward, odometer.Turns)
+	fmt.Fprintf(console, "  сэкономлено клеток: %d, поворотов: %d\n", stats.DistanceSaved(), stats.TurnsSaved())
+}
+
+// WriteTrace выводит трассировку в консоль, если path равен "-", иначе записывает её в файл.
+// В консоли для каждого движения показываются первые клетки, в файл записываются все клетки
+func WriteTrace(path string, trace *rover.Trace, p *plateau.Plateau, source *TraceSource) error {
+	if path == "-" {
+		fmt.Fprintln(console, "Трассировка маршрута:")
+		return PrintTrace(console, trace, p, source, maxTraceCells)
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := PrintTrace(file, trace, p, source, 0); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// PrintTrace выводит таблицу шагов марсохода с исходными командами и посещёнными клетками,
+// limit ограничивает количество клеток одного движения, 0 - без ограничений
+func PrintTrace(out io.Writer, trace *rover.Trace, p *plateau.Plateau, source *TraceSource, limit int) error {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "Команда\tИсходные команды\tДействие\tПозиция\tНаправление\tКлетки")
+	for _, step := range trace.Steps {
+		index := "-"
+		if step.Index >= 0 {
+			index = strconv.Itoa(step.Index)
+		}
+
+		var action, position, direction string
+		cells := "-"
+		switch step.Type {
+		case models.Movement:
+			action = fmt.Sprintf("вперёд %d", step.Value)
+			if step.Value < 0 {
+				action = fmt.Sprintf("назад %d", -step.Value)
+			}
+			position = fmt.Sprintf("(%d, %d) -> (%d, %d)", step.From.X, step.From.Y, step.To.X, step.To.Y)
+			direction = string(step.Heading)
+			cells = formatCells(step, p, limit)
+		case models.Rotation:
+			action = fmt.Sprintf("налево %d", step.Value)
+			if step.Value < 0 {
+				action = fmt.Sprintf("направо %d", -step.Value)
+			}
+			position = fmt.Sprintf("(%d, %d)", step.From.X, step.From.Y)
+			direction = fmt.Sprintf("%s -> %s", step.Heading, step.Direction)
+		}
+		if step.Err != nil {
+			action += ", остановка"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", index, source.fragment(step.Span), action, position, direction, cells)
+	}
+	return w.Flush()
+}
+
+// formatCells записывает клетки движения через пробел, после limit клеток выводится количество оставшихся
+func formatCells(step rover.Step, p *plateau.Plateau, limit int) string {
+	var cells []string
+	step.Cells(p, func(cell models.Coordinates) bool {
+		cells = append(cells, fmt.Sprintf("(%d, %d)", cell.X, cell.Y))
+		return limit == 0 || len(cells) < limit
+	})
+	if len(cells) == 0 {
+		return "-"
+	}
+
+	total := step.Value
+	if total < 0 {
+		total = -total
+	}
+	if rest := total - len(cells); rest > 0 {
+		cells = append(cells, fmt.Sprintf("... ещё %d", rest))
+	}
+	return strings.Join(cells, " ")
 }
 
 func SelectMode() (string, error) {
 	prompt := promptui.Select{
 		Label: "Выберите режим",
-		Items: []string{"Ввести маршрут с консоли", "Загрузить маршрут из файла", "Интерактивное управление стрелками"},
+		Items: []string{
+			"Ввести маршрут с консоли",
+			"Загрузить маршрут из файла",
+			"Интерактивное управление стрелками",
+			"Загрузить группу марсоходов из файла",
+		},
 	}
 
 	_, result, err := prompt.Run()
@@ -109,13 +700,15 @@ func SelectMode() (string, error) {
 		return ModeFile, nil
 	case "Интерактивное управление стрелками":
 		return ModeInteractive, nil
+	case "Загрузить группу марсоходов из файла":
+		return ModeFleet, nil
 	default:
 		return "", errors.New("неверный выбор режима")
 	}
 }
 
 func GetCommandsFromConsole() (string, error) {
-	fmt.Print("Введите маршрут: ")
+	fmt.Fprint(console, "Введите маршрут: ")
 	reader := bufio.NewReader(os.Stdin)
 	commands, err := reader.ReadString('\n')
 	if err != nil {
@@ -124,16 +717,153 @@ func GetCommandsFromConsole() (string, error) {
 	return strings.TrimSpace(commands), nil
 }
 
-func GetCommandsFromFile(filePath string) (string, error) {
+// GetRouteFromFile читает файл маршрута, который может начинаться с заголовка "start x y направление"
+func GetRouteFromFile(filePath string) (*routefile.File, error) {
+	if filePath == "" {
+		fmt.Fprint(console, "Введите путь к файлу: ")
+		fmt.Scan(&filePath)
+	}
+	route, err := routefile.Load(filePath)
+	// ошибки в самом маршруте возвращаются без обёртки, чтобы их подробности не зависели от источника команд
+	if ExitCode(err) == ExitInvalidRoute {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("ошибка чтения файла: %w", err)
+	}
+	return route, nil
+}
+
+// HandleClassicFile выполняет задание в стандартном формате Mars Rover и возвращает результат каждого марсохода
+// с его номером в задании и код завершения по худшему из них. Размер плато берётся из задания, поведение на краю
+// и препятствия - из флагов, оптимизатор strategy создаётся для плато задания
+func HandleClassicFile(filePath, edge, strategy string, p *plateau.Plateau) ([]Result, int, error) {
+	if filePath == "" {
+		fmt.Fprint(console, "Введите путь к файлу: ")
+		fmt.Scan(&filePath)
+	}
+	m, err := mission.Load(filePath)
+	if err != nil {
+		return nil, ExitFailure, err
+	}
+
+	m.Plateau.Edge, err = plateau.ParseEdgeMode(edge)
+	if err != nil {
+		return nil, ExitFailure, err
+	}
+	if p != nil {
+		m.Plateau.Obstacles = p.Obstacles
+	}
+	optimizer, err := optimization.New(strategy, optimization.WithPlateau(m.Plateau))
+	if err != nil {
+		return nil, ExitFailure, err
+	}
+
+	var results []Result
+	status := ExitOK
+	for i, result := range m.Run(optimizer) {
+		results = append(results, NewRoverResult(strconv.Itoa(i+1), result.Pos, result.Direction, result.Err))
+		status = max(status, ExitCode(result.Err))
+	}
+	return results, status, nil
+}
+
+// PrintClassicResults выводит состояние каждого марсохода в формате задания: "1 3 N", и причину остановки
+func PrintClassicResults(results []Result) {
+	for _, result := range results {
+		fmt.Printf("%d %d %s\n", result.Position.X, result.Position.Y, result.Direction)
+		if result.Error != nil {
+			fmt.Println(result.Error.Message)
+		}
+	}
+}
+
+// HandleRemoteInteractiveMode управляет марсоходом сервера rover serve: команды с клавиатуры отправляются
+// по WebSocket на url, ответы сервера выводятся в консоль
+func HandleRemoteInteractiveMode(a *app.App, url string) error {
+	conn, resp, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		return fmt.Errorf("не удалось подключиться к %s: %w", url, err)
+	}
+	_ = resp.Body.Close()
+	defer conn.Close()
+
+	input := make(chan string)
+	go func() {
+		err := a.CaptureInput(input)
+		if err != nil {
+			fmt.Printf("Ошибка ввода: %v\n", err)
+		}
+	}()
+	go func() {
+		for command := range input {
+			if conn.WriteMessage(websocket.TextMessage, []byte(command)) != nil {
+				return
+			}
+		}
+	}()
+
+	for {
+		var msg server.ControlMessage
+		if err := conn.ReadJSON(&msg); err != nil {
+			if websocket.IsCloseError(err, websocket.CloseNormalClosure) {
+				return nil
+			}
+			return err
+		}
+		fmt.Println(msg.Message)
+	}
+}
+
+// HandleFleetMode запускает группу марсоходов из файла и возвращает итоговое состояние каждого
+// и код завершения по худшему из них
+func HandleFleetMode(filePath string, p *plateau.Plateau, optimizer app.Optimizer, interleaved bool) ([]Result, int, error) {
 	if filePath == "" {
-		fmt.Print("Введите путь к файлу: ")
+		fmt.Fprint(console, "Введите путь к файлу: ")
 		fmt.Scan(&filePath)
 	}
-	content, err := os.ReadFile(filePath)
+	specs, err := fleet.LoadFleet(filePath)
 	if err != nil {
-		return "", fmt.Errorf("ошибка чтения файла: %v", err)
+		return nil, ExitFailure, err
+	}
+
+	f := fleet.NewFleet(p, optimizer)
+	for _, spec := range specs {
+		if err := f.Add(spec.Name, spec.Start, spec.Direction, spec.Commands); err != nil {
+			return nil, ExitFailure, err
+		}
+	}
+
+	mode := fleet.Sequential
+	if interleaved {
+		mode = fleet.Interleaved
+	}
+	fleetResults, err := f.Run(mode)
+	if err != nil {
+		return nil, ExitFailure, err
+	}
+
+	var results []Result
+	status := ExitOK
+	for _, result := range fleetResults {
+		results = append(results, NewRoverResult(result.Name, result.Pos, result.Direction, result.Err))
+		status = max(status, ExitCode(result.Err))
+	}
+	return results, status, nil
+}
+
+// PrintFleetResults выводит таблицу с итоговым состоянием каждого марсохода группы
+func PrintFleetResults(results []Result) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "Марсоход\tПозиция\tНаправление\tСтатус")
+	for _, result := range results {
+		status := "маршрут выполнен"
+		if result.Error != nil {
+			status = result.Error.Message
+		}
+		fmt.Fprintf(w, "%s\t(%d, %d)\t%s\t%s\n", result.Name, result.Position.X, result.Position.Y, result.Direction, status)
 	}
-	return strings.TrimSpace(string(content)), nil
+	w.Flush()
 }
 
 func HandleInteractiveMode(a *app.App) error {
@@ -2,32 +2,73 @@ package main
 
 import (
 	"bytes"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"gopkg.in/yaml.v3"
+	"mars-rover/internal/api"
+	"mars-rover/internal/models"
+	"mars-rover/internal/plateau"
+	"mars-rover/internal/rover"
 	"os"
 	"os/exec"
+	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
 )
 
-var testFilePath string
+// roverPath собранный для тестов rover: go run не передаёт код завершения программы
+var roverPath string
+
+// fixtures файлы маршрутов, которые TestMain создаёт в каталоге пакета перед тестами и удаляет после них
+var fixtures = []struct {
+	name    string
+	content string
+}{
+	{name: "testfile.txt", content: "FFLRB\n"},
+	{name: "obstacles.txt", content: "# камень\n1 4\n"},
+	{name: "fleet.txt", content: "alpha 1 2 N FF\nbeta 1 0 N FFFFF\n"},
+	{name: "header.txt", content: "start 3 4 E\nFFL\n"},
+	{name: "macro.txt", content: "def square = 4(FFR)\nsquare\nF\n"},
+	{name: "annotated.txt", content: "# разведка\nFF  # вперёд\n\nL R\nB\nFXF\n"},
+	{name: "syntax.txt", content: "FF\n2(FF\n"},
+	{name: "rock.txt", content: "# к камню\nFF\n  2F\n"},
+	{name: "classic.txt", content: "5 5\n1 1 N\nMMMMMMBB\n"},
+}
 
 func TestMain(m *testing.M) {
 	// Setup phase
-	testFilePath = "testfile.txt"
-	content := []byte("FFLRB\n")
-	err := os.WriteFile(testFilePath, content, 0644)
+	buildDir, err := os.MkdirTemp("", "rover")
 	if err != nil {
-		fmt.Printf("Ошибка при создании тестового файла: %v\n", err)
+		fmt.Printf("Ошибка при создании каталога сборки: %v\n", err)
+		os.Exit(1)
+	}
+	roverPath = filepath.Join(buildDir, "rover")
+	if out, err := exec.Command("go", "build", "-o", roverPath, ".").CombinedOutput(); err != nil {
+		fmt.Printf("Ошибка сборки rover: %v\n%s", err, out)
 		os.Exit(1)
 	}
 
+	for _, f := range fixtures {
+		if err := os.WriteFile(f.name, []byte(f.content), 0644); err != nil {
+			fmt.Printf("Ошибка при создании тестового файла: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
 	// Run the tests
 	exitVal := m.Run()
 
 	// Teardown phase
-	err = os.Remove(testFilePath)
-	if err != nil {
-		fmt.Printf("Ошибка при удалении тестового файла: %v\n", err)
+	for _, f := range fixtures {
+		if err := os.Remove(f.name); err != nil {
+			fmt.Printf("Ошибка при удалении тестового файла: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	if err := os.RemoveAll(buildDir); err != nil {
+		fmt.Printf("Ошибка при удалении каталога сборки: %v\n", err)
 		os.Exit(1)
 	}
 
@@ -40,6 +81,8 @@ func TestMainE2E(t *testing.T) {
 		args           []string
 		input          string
 		expectedOutput []string
+		// exitCode ожидаемый код завершения
+		exitCode int
 	}{
 		{
 			name:           "Console mode with valid commands",
@@ -52,6 +95,282 @@ func TestMainE2E(t *testing.T) {
 			args:           []string{"--mode=file", "--file=testfile.txt"},
 			expectedOutput: []string{"Добро пожаловать в центр управления марсоходом 'Curiosity'!", "Расчёт выполнен успешно. Конечное положение Марсохода: (1, 2), направление: N\n"},
 		},
+		{
+			name:     "Console mode with plateau edge",
+			exitCode: ExitRoverStopped,
+			args:     []string{"--mode=console", "--width=3", "--height=3", "--edge=reject"},
+			input:    "FFF\n",
+			expectedOutput: []string{"Марсоход упёрся в границу плато на команде с индексом 1 (исходные команды [0, 3)). " +
+				"Марсоход остановился в точке (1, 2), направление: N\n"},
+		},
+		{
+			name:     "Console mode with obstacles",
+			exitCode: ExitRoverStopped,
+			args:     []string{"--mode=console", "--obstacles=obstacles.txt"},
+			input:    "FFFFF\n",
+			expectedOutput: []string{"Марсоход остановился перед препятствием на команде с индексом 2 (исходные команды [0, 5)). " +
+				"Последняя безопасная точка (1, 3), направление: N\n"},
+		},
+		{
+			name:     "File mode with obstacles",
+			exitCode: ExitRoverStopped,
+			args:     []string{"--mode=file", "--file=rock.txt", "--obstacles=obstacles.txt"},
+			expectedOutput: []string{"Марсоход остановился перед препятствием на команде в rock.txt:3:3. " +
+				"Последняя безопасная точка (1, 3), направление: N\n"},
+		},
+		{
+			name:  "Console mode with obstacle-safe optimizer",
+			args:  []string{"--mode=console", "--obstacles=obstacles.txt", "--safe"},
+			input: "FFBR\n",
+			expectedOutput: []string{
+				"Конечное положение Марсохода: (1, 2), направление: E\n",
+				"Отчёт оптимизации:\n  команды [0, 3) FFB => F, схлопнут\n  команды [3, 4) R => R, схлопнут\n",
+			},
+		},
+		{
+			name:           "Safe flag with explicit optimizer",
+			exitCode:       ExitFailure,
+			args:           []string{"--mode=console", "--safe", "--optimize=minimal"},
+			input:          "FF\n",
+			expectedOutput: []string{"Флаг --safe выбирает стратегию obstacle-aware, его нельзя указывать вместе с --optimize\n"},
+		},
+		{
+			name:           "Console mode with start state flags",
+			args:           []string{"--mode=console", "--x=3", "--y=4", "--dir=E"},
+			input:          "FF\n",
+			expectedOutput: []string{"Конечное положение Марсохода: (5, 4), направление: E\n"},
+		},
+		{
+			name:           "Console mode with repeated group",
+			args:           []string{"--mode=console"},
+			input:          "3(FFR)\n",
+			expectedOutput: []string{"Конечное положение Марсохода: (3, 1), направление: W\n"},
+		},
+		{
+			name:           "Console mode with unclosed group",
+			exitCode:       ExitInvalidRoute,
+			args:           []string{"--mode=console"},
+			input:          "2(FF\n",
+			expectedOutput: []string{"Некорректный синтаксис маршрута: syntax error: malformed route: unclosed '(' at offset 1"},
+		},
+		{
+			name:     "Console mode with invalid symbols",
+			exitCode: ExitInvalidRoute,
+			args:     []string{"--mode=console"},
+			input:    "FFXF?\n",
+			expectedOutput: []string{
+				"Некорректный путь, путь должен состоять только из символов F, B, R, L. Недопустимых символов: 2\n" +
+					"  FFXF?\n" +
+					"    ^ символ 'X', смещение 2\n" +
+					"  FFXF?\n" +
+					"      ^ символ '?', смещение 4\n",
+			},
+		},
+		{
+			name:     "Console mode with trace",
+			exitCode: ExitRoverStopped,
+			args:     []string{"--mode=console", "--obstacles=obstacles.txt", "--trace=-"},
+			input:    "RLFFF\n",
+			expectedOutput: []string{
+				"Трассировка маршрута:",
+				"Команда  Исходные команды  Действие",
+				"[2, 5) FFF",
+				"вперёд 2, остановка  (1, 1) -> (1, 3)  N            (1, 2) (1, 3)",
+			},
+		},
+		{
+			name:  "Console mode with stats",
+			args:  []string{"--mode=console", "--stats"},
+			input: "FFFBBLLLR\n",
+			expectedOutput: []string{
+				"Конечное положение Марсохода: (1, 2), направление: S\n" +
+					"Статистика маршрута:\n" +
+					"  команд: 9, движений после оптимизации: 2\n" +
+					"  пробег: вперёд 1, назад 0, поворотов 2\n" +
+					"  сэкономлено клеток: 4, поворотов: 2\n",
+			},
+		},
+		{
+			name:     "Console mode with battery",
+			exitCode: ExitRoverStopped,
+			args:     []string{"--mode=console", "--battery=5", "--energy=1,1,2,0"},
+			input:    "FFRFFF\n",
+			expectedOutput: []string{"У марсохода разрядилась батарея на команде с индексом 4 (исходные команды [3, 6)). " +
+				"Марсоход остановился в точке (2, 3), направление: E\n"},
+		},
+		{
+			name:           "Console mode with remaining charge",
+			args:           []string{"--mode=console", "--battery=10"},
+			input:          "FFL\n",
+			expectedOutput: []string{"Конечное положение Марсохода: (1, 3), направление: W\nОставшийся заряд батареи: 7\n"},
+		},
+		{
+			name:           "Console mode with invalid energy model",
+			exitCode:       ExitFailure,
+			args:           []string{"--mode=console", "--battery=10", "--energy=1,1"},
+			expectedOutput: []string{"Некорректная модель энергии: ожидалось 4 стоимости через запятую, получено \"1,1\""},
+		},
+		{
+			name:           "Plan around obstacle",
+			args:           []string{"plan", "1", "5", "--obstacles=obstacles.txt"},
+			expectedOutput: []string{"Маршрут до (1, 5): ", "Команд: 9\n"},
+		},
+		{
+			name:           "Plan with target heading",
+			args:           []string{"plan", "3", "1", "E", "--width=5", "--height=5"},
+			expectedOutput: []string{"Маршрут до (3, 1): RFF\nКоманд: 3\n"},
+		},
+		{
+			name:           "Plan to unreachable cell",
+			exitCode:       ExitFailure,
+			args:           []string{"plan", "7", "1", "--width=5", "--height=5"},
+			expectedOutput: []string{"Ошибка планирования маршрута: planner error: target is unreachable: (7, 1) is outside the plateau or blocked\n"},
+		},
+		{
+			name:  "Console mode with minimal optimizer",
+			args:  []string{"--mode=console", "--optimize=minimal", "--stats"},
+			input: "FRFRFRFRFL\n",
+			expectedOutput: []string{
+				"Конечное положение Марсохода: (1, 2), направление: W\n",
+				"  команд: 10, движений после оптимизации: 2\n",
+			},
+		},
+		{
+			name:     "Console mode without optimization",
+			exitCode: ExitRoverStopped,
+			args:     []string{"--mode=console", "--optimize=none", "--obstacles=obstacles.txt"},
+			input:    "FFFFF\n",
+			expectedOutput: []string{"Марсоход остановился перед препятствием на команде с индексом 2 (исходные команды [2, 3)). " +
+				"Последняя безопасная точка (1, 3), направление: N\n"},
+		},
+		{
+			name:           "Console mode with unknown optimizer",
+			exitCode:       ExitFailure,
+			args:           []string{"--mode=console", "--optimize=fast"},
+			expectedOutput: []string{"Некорректная стратегия оптимизации: optimizer error: unknown strategy: \"fast\"\n"},
+		},
+		{
+			name:  "Console mode with obstacle-aware strategy",
+			args:  []string{"--mode=console", "--obstacles=obstacles.txt", "--optimize=obstacle-aware"},
+			input: "FFBR\n",
+			expectedOutput: []string{
+				"Отчёт оптимизации:\n  команды [0, 3) FFB => F, схлопнут\n",
+			},
+		},
+		{
+			name: "List optimizers",
+			args: []string{"optimizers"},
+			expectedOutput: []string{
+				"Стратегия       Псевдонимы  Описание\n",
+				"run-length *    runs",
+				"obstacle-aware  safe",
+			},
+		},
+		{
+			name:           "Console mode with invalid direction",
+			exitCode:       ExitFailure,
+			args:           []string{"--mode=console", "--dir=Q"},
+			expectedOutput: []string{"Некорректное начальное направление: validation error: invalid direction: \"Q\""},
+		},
+		{
+			name:           "File mode with start header",
+			args:           []string{"--mode=file", "--file=header.txt"},
+			expectedOutput: []string{"Конечное положение Марсохода: (5, 4), направление: N\n"},
+		},
+		{
+			name:           "File mode with start flags overriding header",
+			args:           []string{"--mode=file", "--file=header.txt", "--dir=S"},
+			expectedOutput: []string{"Конечное положение Марсохода: (3, 2), направление: E\n"},
+		},
+		{
+			name:           "File mode with coordinate flag overriding header",
+			args:           []string{"--mode=file", "--file=header.txt", "--y=0"},
+			expectedOutput: []string{"Конечное положение Марсохода: (5, 0), направление: N\n"},
+		},
+		{
+			name:           "File mode with macros",
+			args:           []string{"--mode=file", "--file=macro.txt"},
+			expectedOutput: []string{"Конечное положение Марсохода: (1, 2), направление: N\n"},
+		},
+		{
+			name:           "File mode with error location",
+			exitCode:       ExitInvalidRoute,
+			args:           []string{"--mode=file", "--file=annotated.txt"},
+			expectedOutput: []string{"Недопустимых символов: 1\n  FXF\n   ^ символ 'X', annotated.txt:6:2\n"},
+		},
+		{
+			name:           "File mode with syntax error",
+			exitCode:       ExitInvalidRoute,
+			args:           []string{"--mode=file", "--file=syntax.txt"},
+			expectedOutput: []string{"Некорректный синтаксис маршрута: syntax.txt:2:2: syntax error: malformed route: unclosed '('\n"},
+		},
+		{
+			name:           "File mode with classic input format",
+			args:           []string{"--mode=file", "--input-format=classic", "--file=../../data/classic_test"},
+			expectedOutput: []string{"1 3 N\n5 1 E\n"},
+		},
+		{
+			// оптимизатор получает плато 5x5 из задания: отрезок за край плато не схлопывается
+			name:     "File mode with classic input format and obstacle-aware optimizer",
+			exitCode: ExitRoverStopped,
+			args: []string{"--mode=file", "--input-format=classic", "--file=classic.txt",
+				"--optimize=obstacle-aware"},
+			expectedOutput: []string{"1 5 N\nМарсоход упёрся в границу плато"},
+		},
+		{
+			name:           "File mode with stream input format",
+			exitCode:       ExitInvalidRoute,
+			args:           []string{"--mode=file", "--input-format=stream", "--file=annotated.txt"},
+			expectedOutput: []string{"Недопустимых символов: 1\n  X\n  ^ символ 'X', смещение 46\n"},
+		},
+		{
+			name:           "File mode with stream input format and groups",
+			exitCode:       ExitInvalidRoute,
+			args:           []string{"--mode=file", "--input-format=stream", "--file=macro.txt"},
+			expectedOutput: []string{"символ 'd', смещение 0"},
+		},
+		{
+			name:           "File mode with stream input format success",
+			args:           []string{"--mode=file", "--input-format=stream", "--file=testfile.txt"},
+			expectedOutput: []string{"Конечное положение Марсохода: (1, 2), направление: N"},
+		},
+		{
+			name:     "Fleet mode",
+			exitCode: ExitRoverStopped,
+			args:     []string{"--mode=fleet", "--file=fleet.txt"},
+			expectedOutput: []string{
+				"Марсоход  Позиция  Направление  Статус",
+				"alpha     (1, 4)   N            маршрут выполнен",
+				"beta      (1, 3)   N            Марсоход остановился, чтобы не столкнуться с другим марсоходом",
+			},
+		},
+		{
+			name:           "Unknown output format",
+			args:           []string{"--mode=console", "--output=xml"},
+			exitCode:       ExitFailure,
+			expectedOutput: []string{"неизвестный формат вывода \"xml\""},
+		},
+		{
+			name:           "Interactive mode with json output",
+			args:           []string{"--mode=interactive", "--output=json"},
+			exitCode:       ExitFailure,
+			expectedOutput: []string{`"code": "invalid_request"`, "Интерактивный режим поддерживает только --output=text"},
+		},
+		{
+			name:           "Classic input format with csv output",
+			args:           []string{"--mode=file", "--input-format=classic", "--file=../../data/classic_test", "-o", "csv"},
+			expectedOutput: []string{"name,x,y,direction,moves,battery,error_code,error_message\n1,1,3,N,,,,\n2,5,1,E,,,,\n"},
+		},
+		{
+			name:     "Fleet mode with yaml output",
+			args:     []string{"--mode=fleet", "--file=fleet.txt", "--output=yaml"},
+			exitCode: ExitRoverStopped,
+			expectedOutput: []string{
+				"- name: alpha\n  position:\n    x: 1\n    \"y\": 4\n  direction: \"N\"\n",
+				"- name: beta\n",
+				"    code: rover_collision\n",
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -60,17 +379,23 @@ func TestMainE2E(t *testing.T) {
 			var stdout, stderr bytes.Buffer
 
 			// Prepare the command
-			cmd := exec.Command("go", "run", ".")
-			cmd.Args = append(cmd.Args, tt.args...)
+			cmd := exec.Command(roverPath, tt.args...)
 			cmd.Stdin = bytes.NewBufferString(tt.input)
 			cmd.Stdout = &stdout
 			cmd.Stderr = &stderr
 
 			// Run the command
 			err := cmd.Run()
-			if err != nil && tt.name != "Interactive mode" {
+			exitCode := 0
+			var exitErr *exec.ExitError
+			if errors.As(err, &exitErr) {
+				exitCode = exitErr.ExitCode()
+			} else if err != nil {
 				t.Fatalf("Ошибка выполнения команды: %v\nstderr: %v", err, stderr.String())
 			}
+			if exitCode != tt.exitCode {
+				t.Errorf("Ожидался код завершения %d, получили %d\nstdout: %v", tt.exitCode, exitCode, stdout.String())
+			}
 
 			output := stdout.String()
 			for _, expected := range tt.expectedOutput {
@@ -81,3 +406,177 @@ func TestMainE2E(t *testing.T) {
 		})
 	}
 }
+
+func TestMachineOutput(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		input    string
+		exitCode int
+		expected Result
+	}{
+		{
+			name:  "Console mode",
+			args:  []string{"--mode=console", "--battery=10"},
+			input: "FFLRB\n",
+			expected: Result{
+				RouteResponse: api.RouteResponse{
+					Position:  &api.Position{X: 1, Y: 2},
+					Direction: "N",
+					Moves: []api.Move{
+						{Type: models.Movement, Value: 2, Span: &api.Span{Start: 0, End: 2}},
+						{Type: models.Movement, Value: -1, Span: &api.Span{Start: 4, End: 5}},
+					},
+				},
+				Battery: intPtr(7),
+			},
+		},
+		{
+			name:     "Obstacle",
+			args:     []string{"--mode=console", "--obstacles=obstacles.txt"},
+			input:    "FFFFF\n",
+			exitCode: ExitRoverStopped,
+			expected: Result{
+				RouteResponse: api.RouteResponse{
+					Position:  &api.Position{X: 1, Y: 3},
+					Direction: "N",
+					Moves:     []api.Move{{Type: models.Movement, Value: 5, Span: &api.Span{Start: 0, End: 5}}},
+					Error: &api.Error{
+						Code: api.CodeObstacle,
+						Message: "Марсоход остановился перед препятствием на команде с индексом 2 (исходные команды [0, 5)). " +
+							"Последняя безопасная точка (1, 3), направление: N",
+						Detail: "route stopped at command 2, position (1, 3) N: " +
+							"collision error: cell is blocked by an obstacle: (1, 4)",
+						Index: intPtr(2),
+						Span:  &api.Span{Start: 0, End: 5},
+					},
+				},
+			},
+		},
+		{
+			name:     "Invalid symbols",
+			args:     []string{"--mode=console"},
+			input:    "FXF\n",
+			exitCode: ExitInvalidRoute,
+			expected: Result{
+				RouteResponse: api.RouteResponse{
+					Error: &api.Error{
+						Code: api.CodeInvalidSymbol,
+						Message: "Некорректный путь, путь должен состоять только из символов F, B, R, L. " +
+							"Недопустимых символов: 1\n  FXF\n   ^ символ 'X', смещение 1",
+						Detail:  "validation error: unexpected input: 'X' at offset 1",
+						Symbols: []api.Symbol{{Symbol: "X", Offset: 1}},
+					},
+				},
+			},
+		},
+		{
+			name:     "Syntax error in file",
+			args:     []string{"--mode=file", "--file=syntax.txt"},
+			exitCode: ExitInvalidRoute,
+			expected: Result{
+				RouteResponse: api.RouteResponse{
+					Error: &api.Error{
+						Code:    api.CodeSyntax,
+						Message: "Некорректный синтаксис маршрута: syntax.txt:2:2: syntax error: malformed route: unclosed '('",
+						Detail:  "syntax.txt:2:2: syntax error: malformed route: unclosed '('",
+					},
+				},
+			},
+		},
+		{
+			name:     "Missing file",
+			args:     []string{"--mode=file", "--file=missing.txt"},
+			exitCode: ExitFailure,
+			expected: Result{
+				RouteResponse: api.RouteResponse{
+					Error: &api.Error{
+						Code:    api.CodeInvalidRequest,
+						Message: "Ошибка получения команд: ошибка чтения файла: open missing.txt: no such file or directory",
+					},
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		for _, format := range []string{OutputJSON, OutputYAML} {
+			t.Run(tt.name+" "+format, func(t *testing.T) {
+				var stdout, stderr bytes.Buffer
+				cmd := exec.Command(roverPath, append(tt.args, "--output="+format)...)
+				cmd.Stdin = bytes.NewBufferString(tt.input)
+				cmd.Stdout = &stdout
+				cmd.Stderr = &stderr
+
+				err := cmd.Run()
+				exitCode := 0
+				var exitErr *exec.ExitError
+				if errors.As(err, &exitErr) {
+					exitCode = exitErr.ExitCode()
+				} else if err != nil {
+					t.Fatalf("Ошибка выполнения команды: %v\nstderr: %v", err, stderr.String())
+				}
+				if exitCode != tt.exitCode {
+					t.Errorf("Ожидался код завершения %d, получили %d", tt.exitCode, exitCode)
+				}
+
+				// в stdout только результат, приветствие и приглашение ввода уходят в stderr
+				var result Result
+				if format == OutputJSON {
+					err = json.Unmarshal(stdout.Bytes(), &result)
+				} else {
+					err = yaml.Unmarshal(stdout.Bytes(), &result)
+				}
+				if err != nil {
+					t.Fatalf("Результат не разбирается: %v\n%s", err, stdout.String())
+				}
+				if !reflect.DeepEqual(tt.expected, result) {
+					t.Errorf("Ожидался результат %+v, получили %s", tt.expected, stdout.String())
+				}
+				if !strings.Contains(stderr.String(), "Добро пожаловать") {
+					t.Errorf("Ожидалось приветствие в stderr, получили %q", stderr.String())
+				}
+			})
+		}
+	}
+}
+
+func intPtr(v int) *int {
+	return &v
+}
+
+func TestWriteTrace(t *testing.T) {
+	trace := rover.NewTrace()
+	p := &plateau.Plateau{Width: 3, Height: 3, Edge: plateau.EdgeWrap}
+	r := rover.NewRover(rover.WithPlateau(p), rover.WithRecorder(trace))
+	if err := r.PerformRouteWithSpans([]models.Move{
+		{Type: models.Movement, Value: 25},
+		{Type: models.Rotation, Value: -1},
+	}, []models.Span{{Start: 0, End: 3}, {Start: 3, End: 4}}); err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "trace.txt")
+	source := &TraceSource{Commands: "25FR"}
+	if err := WriteTrace(path, trace, p, source); err != nil {
+		t.Fatal(err)
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("Ожидалось 3 строки трассировки, получили %q", content)
+	}
+	if cells := strings.Count(lines[1], "("); cells != 27 {
+		t.Errorf("Ожидалось 25 посещённых клеток в файле, получили %d в строке %q", cells-2, lines[1])
+	}
+	if !strings.Contains(lines[1], "[0, 3) 25F") {
+		t.Errorf("Ожидались исходные команды движения, получили %q", lines[1])
+	}
+	if !strings.Contains(lines[2], "направо 1") || !strings.Contains(lines[2], "N -> E") {
+		t.Errorf("Ожидался поворот направо, получили %q", lines[2])
+	}
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"io"
+	"mars-rover/internal/api"
+	"mars-rover/internal/models"
+	"mars-rover/internal/optimization"
+	"os"
+	"strconv"
+)
+
+const (
+	OutputText = "text"
+	OutputJSON = "json"
+	OutputYAML = "yaml"
+	OutputCSV  = "csv"
+)
+
+// Коды завершения rover
+const (
+	ExitOK = 0
+	// ExitFailure ошибка запуска: флаги, файлы, чтение ввода, маршрут слишком длинный для выбранной стратегии
+	ExitFailure = 1
+	// ExitInvalidRoute недопустимые символы или синтаксическая ошибка в маршруте, марсоход не двигался
+	ExitInvalidRoute = 2
+	// ExitRoverStopped марсоход остановился, не выполнив маршрут: препятствие, граница плато, батарея, столкновение
+	ExitRoverStopped = 3
+)
+
+// console куда выводятся приветствие, приглашения ввода, трассировка и отчёты. В форматах json, yaml и csv
+// это stderr, чтобы в stdout был только результат
+var console io.Writer = os.Stdout
+
+// csvHeader колонки результата в формате csv
+var csvHeader = []string{"name", "x", "y", "direction", "moves", "battery", "error_code", "error_message"}
+
+// Result результат марсохода в форматах json, yaml и csv: поля api.RouteResponse, имя марсохода
+// в режиме fleet (номер в задании classic) и оставшийся заряд батареи
+type Result struct {
+	Name              string `json:"name,omitempty" yaml:"name,omitempty"`
+	api.RouteResponse `yaml:",inline"`
+	Battery           *int `json:"battery,omitempty" yaml:"battery,omitempty"`
+}
+
+// NewResult результат маршрута, который марсоход закончил в position и direction с ошибкой err
+func NewResult(position models.Coordinates, direction models.Direction, route []models.Move, spans []models.Span,
+	err error) Result {
+	return Result{RouteResponse: *api.NewRouteResponse(position, direction, route, spans, err)}
+}
+
+// NewRoverResult результат марсохода группы с именем name. Положение выводится, даже если марсоход не начал
+// маршрут, например из-за старта за пределами плато
+func NewRoverResult(name string, position models.Coordinates, direction models.Direction, err error) Result {
+	result := NewResult(position, direction, nil, nil, err)
+	result.Name = name
+	if result.Position == nil {
+		result.Position = &api.Position{X: position.X, Y: position.Y}
+		result.Direction = string(direction)
+	}
+	return result
+}
+
+// FailureResult результат с ошибкой запуска, после которой марсоход не двигался
+func FailureResult(code, message string) Result {
+	return Result{RouteResponse: api.RouteResponse{Error: &api.Error{Code: code, Message: message}}}
+}
+
+// ParseOutput проверяет формат вывода
+func ParseOutput(format string) (string, error) {
+	switch format {
+	case OutputText, OutputJSON, OutputYAML, OutputCSV:
+		return format, nil
+	}
+	return "", fmt.Errorf("неизвестный формат вывода %q, используйте text, json, yaml или csv", format)
+}
+
+// WriteResult выводит результат одного марсохода: объект в json и yaml, строку после заголовка в csv
+func WriteResult(out io.Writer, format string, result Result) error {
+	if format == OutputCSV {
+		return writeCSV(out, []Result{result})
+	}
+	return encode(out, format, result)
+}
+
+// PrintResults выводит результаты группы марсоходов в stdout: в формате text функцией text, в остальных - WriteResults
+func PrintResults(format string, results []Result, text func([]Result)) {
+	if format == OutputText {
+		text(results)
+		return
+	}
+	if err := WriteResults(os.Stdout, format, results); err != nil {
+		fmt.Fprintf(os.Stderr, "Ошибка вывода результата: %v\n", err)
+	}
+}
+
+// WriteResults выводит результаты группы марсоходов: список в json и yaml, по строке на марсоход в csv
+func WriteResults(out io.Writer, format string, results []Result) error {
+	if format == OutputCSV {
+		return writeCSV(out, results)
+	}
+	if results == nil {
+		results = []Result{}
+	}
+	return encode(out, format, results)
+}
+
+func encode(out io.Writer, format string, v any) error {
+	switch format {
+	case OutputJSON:
+		enc := json.NewEncoder(out)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v)
+	case OutputYAML:
+		enc := yaml.NewEncoder(out)
+		enc.SetIndent(2)
+		if err := enc.Encode(v); err != nil {
+			return err
+		}
+		return enc.Close()
+	default:
+		return fmt.Errorf("формат вывода %q не поддерживает результат марсохода", format)
+	}
+}
+
+// writeCSV выводит результаты по строке на марсоход, движения записываются строкой команд: 2FL3F
+func writeCSV(out io.Writer, results []Result) error {
+	w := csv.NewWriter(out)
+	if err := w.Write(csvHeader); err != nil {
+		return err
+	}
+	for _, result := range results {
+		record := make([]string, len(csvHeader))
+		record[0] = result.Name
+		if result.Position != nil {
+			record[1], record[2] = strconv.Itoa(result.Position.X), strconv.Itoa(result.Position.Y)
+		}
+		record[3] = result.Direction
+		moves := make([]models.Move, 0, len(result.Moves))
+		for _, m := range result.Moves {
+			moves = append(moves, models.Move{Type: m.Type, Value: m.Value})
+		}
+		record[4] = optimization.FormatMoves(moves)
+		if result.Battery != nil {
+			record[5] = strconv.Itoa(*result.Battery)
+		}
+		if result.Error != nil {
+			record[6], record[7] = result.Error.Code, result.Error.Message
+		}
+		if err := w.Write(record); err != nil {
+			return err
+		}
+	}
+	w.Flush()
+	return w.Error()
+}
+
+// ExitCode код завершения для ошибки расчёта маршрута
+func ExitCode(err error) int {
+	switch {
+	case err == nil:
+		return ExitOK
+	case errors.Is(err, models.ErrIncorrectSymbol), errors.Is(err, models.ErrRouteSyntax):
+		return ExitInvalidRoute
+	case errors.Is(err, models.ErrOutOfBounds), errors.Is(err, models.ErrObstacle),
+		errors.Is(err, models.ErrRoverCollision), errors.Is(err, models.ErrBatteryDepleted):
+		return ExitRoverStopped
+	default:
+		return ExitFailure
+	}
+}
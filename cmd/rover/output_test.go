@@ -0,0 +1,107 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"mars-rover/internal/api"
+	"mars-rover/internal/models"
+	"testing"
+)
+
+func TestWriteResults(t *testing.T) {
+	results := []Result{
+		{Name: "1", RouteResponse: api.RouteResponse{Position: &api.Position{X: 1, Y: 3}, Direction: "N"}},
+		{
+			Name: "2",
+			RouteResponse: api.RouteResponse{
+				Position:  &api.Position{X: 5, Y: 1},
+				Direction: "E",
+				Moves: []api.Move{
+					{Type: models.Movement, Value: 2},
+					{Type: models.Rotation, Value: -1},
+					{Type: models.Movement, Value: 3},
+				},
+				Error: &api.Error{Code: api.CodeObstacle, Message: "Препятствие, марсоход остановился"},
+			},
+			Battery: intPtr(4),
+		},
+	}
+
+	tests := []struct {
+		format   string
+		results  []Result
+		expected string
+	}{
+		{
+			format:  OutputCSV,
+			results: results,
+			expected: "name,x,y,direction,moves,battery,error_code,error_message\n" +
+				"1,1,3,N,,,,\n" +
+				"2,5,1,E,2FR3F,4,obstacle,\"Препятствие, марсоход остановился\"\n",
+		},
+		{
+			format:  OutputJSON,
+			results: results[:1],
+			expected: "[\n  {\n    \"name\": \"1\",\n    \"position\": {\n      \"x\": 1,\n      \"y\": 3\n    },\n" +
+				"    \"direction\": \"N\"\n  }\n]\n",
+		},
+		{
+			format:   OutputYAML,
+			results:  results[:1],
+			expected: "- name: \"1\"\n  position:\n    x: 1\n    \"y\": 3\n  direction: \"N\"\n",
+		},
+		{
+			// пустая группа выводится пустым списком, а не null
+			format:   OutputJSON,
+			expected: "[]\n",
+		},
+		{
+			format:   OutputCSV,
+			expected: "name,x,y,direction,moves,battery,error_code,error_message\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%s %d", tt.format, len(tt.results)), func(t *testing.T) {
+			var out bytes.Buffer
+			if err := WriteResults(&out, tt.format, tt.results); err != nil {
+				t.Fatal(err)
+			}
+			if out.String() != tt.expected {
+				t.Errorf("Ожидался вывод %q, получили %q", tt.expected, out.String())
+			}
+		})
+	}
+}
+
+func TestWriteResult_Text(t *testing.T) {
+	if err := WriteResult(&bytes.Buffer{}, OutputText, Result{}); err == nil {
+		t.Error("Ожидалась ошибка вывода результата в формате text")
+	}
+}
+
+func TestExitCode(t *testing.T) {
+	tests := []struct {
+		err      error
+		expected int
+	}{
+		{expected: ExitOK},
+		{err: models.SymbolErrors{{Offset: 1, Symbol: 'X'}}, expected: ExitInvalidRoute},
+		{err: fmt.Errorf("route.txt:2:2: %w", models.ErrRouteSyntax), expected: ExitInvalidRoute},
+		{err: &models.RouteError{Err: models.ErrOutOfBounds}, expected: ExitRoverStopped},
+		{err: &models.RouteError{Err: models.ErrObstacle}, expected: ExitRoverStopped},
+		{err: &models.RouteError{Err: models.ErrRoverCollision}, expected: ExitRoverStopped},
+		{err: &models.RouteError{Err: models.ErrBatteryDepleted}, expected: ExitRoverStopped},
+		{err: fmt.Errorf("%w to run without optimization: 5 commands", models.ErrRouteTooLong), expected: ExitFailure},
+		{err: errors.New("read error"), expected: ExitFailure},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprint(tt.err), func(t *testing.T) {
+			if code := ExitCode(tt.err); code != tt.expected {
+				t.Errorf("Ожидался код завершения %d, получили %d", tt.expected, code)
+			}
+		})
+	}
+}
@@ -0,0 +1,156 @@
+// Package api описывает результат расчёта маршрута, общий для HTTP API, gRPC и вывода rover --output
+package api
+
+import (
+	"errors"
+	"mars-rover/internal/app"
+	"mars-rover/internal/models"
+)
+
+// Коды ошибок в результатах
+const (
+	CodeInvalidRequest   = "invalid_request"
+	CodeInvalidStart     = "invalid_start"
+	CodeUnknownOptimizer = "unknown_optimizer"
+	CodeInvalidSymbol    = "invalid_symbol"
+	CodeSyntax           = "syntax_error"
+	CodeRouteTooLong     = "route_too_long"
+	CodeOutOfBounds      = "out_of_bounds"
+	CodeObstacle         = "obstacle"
+	CodeRoverCollision   = "rover_collision"
+	CodeBatteryDepleted  = "battery_depleted"
+	CodeInternal         = "internal_error"
+)
+
+// Position клетка плато
+type Position struct {
+	X int `json:"x" yaml:"x"`
+	Y int `json:"y" yaml:"y"`
+}
+
+// Move движение оптимизированного маршрута
+type Move struct {
+	Type  models.MoveType `json:"type" yaml:"type"`
+	Value int             `json:"value" yaml:"value"`
+	// Span команды исходного маршрута [start, end), из которых получено движение
+	Span *Span `json:"span,omitempty" yaml:"span,omitempty"`
+}
+
+// Span диапазон команд исходного маршрута [start, end) в байтах
+type Span struct {
+	Start int `json:"start" yaml:"start"`
+	End   int `json:"end" yaml:"end"`
+}
+
+// Symbol недопустимый символ маршрута
+type Symbol struct {
+	Symbol   string `json:"symbol" yaml:"symbol"`
+	Offset   int    `json:"offset" yaml:"offset"`
+	Location string `json:"location,omitempty" yaml:"location,omitempty"`
+}
+
+// Error ошибка в результате. Message - описание для человека, Detail - текст ошибки движка
+type Error struct {
+	Code    string `json:"code" yaml:"code"`
+	Message string `json:"message" yaml:"message"`
+	Detail  string `json:"detail,omitempty" yaml:"detail,omitempty"`
+	// Index смещение в байтах исходной команды, на которой остановился марсоход, нет, если оптимизатор не сообщает
+	// диапазоны. Location место этой команды в файле маршрута, Span команды, из которых получено её движение
+	Index    *int     `json:"index,omitempty" yaml:"index,omitempty"`
+	Location string   `json:"location,omitempty" yaml:"location,omitempty"`
+	Span     *Span    `json:"span,omitempty" yaml:"span,omitempty"`
+	Symbols  []Symbol `json:"symbols,omitempty" yaml:"symbols,omitempty"`
+}
+
+// RouteResponse результат расчёта маршрута. Если марсоход остановился на середине маршрута, Position и Direction -
+// точка остановки, а Error - её причина. В этом виде результат отдаёт POST /api/v1/routes и выводит
+// rover --output=json|yaml
+type RouteResponse struct {
+	Position  *Position `json:"position,omitempty" yaml:"position,omitempty"`
+	Direction string    `json:"direction,omitempty" yaml:"direction,omitempty"`
+	Moves     []Move    `json:"moves,omitempty" yaml:"moves,omitempty"`
+	Error     *Error    `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+// NewRouteResponse собирает результат расчёта маршрута: конечное положение, движения route с диапазонами spans
+// (spans может быть nil) и ошибку err. Если маршрут не выполнялся, например из-за недопустимых символов,
+// в результате только ошибка
+func NewRouteResponse(position models.Coordinates, direction models.Direction, route []models.Move,
+	spans []models.Span, err error) *RouteResponse {
+	var routeErr *models.RouteError
+	if err != nil && !errors.As(err, &routeErr) {
+		return &RouteResponse{Error: NewError(err)}
+	}
+
+	// марсоход, остановившийся на середине маршрута, тоже возвращает точку остановки
+	resp := &RouteResponse{
+		Position:  &Position{X: position.X, Y: position.Y},
+		Direction: string(direction),
+		Moves:     newMoves(route, spans),
+	}
+	if err != nil {
+		resp.Error = NewError(err)
+	}
+	return resp
+}
+
+// newMoves переводит движения маршрута в результат, spans может быть nil
+func newMoves(route []models.Move, spans []models.Span) []Move {
+	moves := make([]Move, 0, len(route))
+	for i, m := range route {
+		move := Move{Type: m.Type, Value: m.Value}
+		if i < len(spans) {
+			move.Span = &Span{Start: spans[i].Start, End: spans[i].End}
+		}
+		moves = append(moves, move)
+	}
+	return moves
+}
+
+// NewError описывает ошибку движка кодом, сообщением app.HandleError и подробностями
+func NewError(err error) *Error {
+	e := &Error{Code: ErrorCode(err), Message: app.HandleError(err), Detail: err.Error()}
+
+	var routeErr *models.RouteError
+	if errors.As(err, &routeErr) {
+		if routeErr.Index >= 0 {
+			index := routeErr.Index
+			e.Index = &index
+		}
+		e.Location = routeErr.Location
+		if routeErr.Span != (models.Span{}) {
+			e.Span = &Span{Start: routeErr.Span.Start, End: routeErr.Span.End}
+		}
+	}
+	var symbolErrs models.SymbolErrors
+	if errors.As(err, &symbolErrs) {
+		for _, symbolErr := range symbolErrs {
+			e.Symbols = append(e.Symbols, Symbol{
+				Symbol: string(symbolErr.Symbol), Offset: symbolErr.Offset, Location: symbolErr.Location,
+			})
+		}
+	}
+	return e
+}
+
+// ErrorCode код ошибки движка
+func ErrorCode(err error) string {
+	switch {
+	case errors.Is(err, models.ErrIncorrectSymbol):
+		return CodeInvalidSymbol
+	case errors.Is(err, models.ErrRouteSyntax):
+		return CodeSyntax
+	case errors.Is(err, models.ErrRouteTooLong):
+		return CodeRouteTooLong
+	case errors.Is(err, models.ErrOutOfBounds):
+		return CodeOutOfBounds
+	case errors.Is(err, models.ErrObstacle):
+		return CodeObstacle
+	case errors.Is(err, models.ErrRoverCollision):
+		return CodeRoverCollision
+	case errors.Is(err, models.ErrBatteryDepleted):
+		return CodeBatteryDepleted
+	default:
+		return CodeInternal
+	}
+}
@@ -0,0 +1,108 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"mars-rover/internal/models"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewRouteResponse(t *testing.T) {
+	index := 4
+	route := []models.Move{{Type: models.Rotation, Value: 1}, {Type: models.Movement, Value: 3}}
+	spans := []models.Span{{Start: 0, End: 1}, {Start: 1, End: 4}}
+
+	tests := []struct {
+		name     string
+		err      error
+		expected *RouteResponse
+	}{
+		{
+			name: "Route completed",
+			expected: &RouteResponse{
+				Position:  &Position{X: 3, Y: 1},
+				Direction: "E",
+				Moves: []Move{
+					{Type: models.Rotation, Value: 1, Span: &Span{Start: 0, End: 1}},
+					{Type: models.Movement, Value: 3, Span: &Span{Start: 1, End: 4}},
+				},
+			},
+		},
+		{
+			name: "Rover stopped",
+			err: &models.RouteError{Index: 4, Move: 1, Location: "route.txt:2:3", Pos: models.Coordinates{X: 3, Y: 1},
+				Direction: models.East, Span: models.Span{Start: 1, End: 4}, Err: models.ErrObstacle},
+			expected: &RouteResponse{
+				Position:  &Position{X: 3, Y: 1},
+				Direction: "E",
+				Moves: []Move{
+					{Type: models.Rotation, Value: 1, Span: &Span{Start: 0, End: 1}},
+					{Type: models.Movement, Value: 3, Span: &Span{Start: 1, End: 4}},
+				},
+				Error: &Error{
+					Code:     CodeObstacle,
+					Index:    &index,
+					Location: "route.txt:2:3",
+					Span:     &Span{Start: 1, End: 4},
+				},
+			},
+		},
+		{
+			// оптимизатор без диапазонов: исходная команда неизвестна, смещение не сообщается
+			name: "Source command unknown",
+			err:  &models.RouteError{Index: -1, Move: 1, Pos: models.Coordinates{X: 3, Y: 1}, Err: models.ErrObstacle},
+			expected: &RouteResponse{
+				Position:  &Position{X: 3, Y: 1},
+				Direction: "E",
+				Moves: []Move{
+					{Type: models.Rotation, Value: 1, Span: &Span{Start: 0, End: 1}},
+					{Type: models.Movement, Value: 3, Span: &Span{Start: 1, End: 4}},
+				},
+				Error: &Error{Code: CodeObstacle},
+			},
+		},
+		{
+			name: "Route not started",
+			err:  models.SymbolErrors{{Offset: 2, Symbol: 'X'}},
+			expected: &RouteResponse{
+				Error: &Error{Code: CodeInvalidSymbol, Symbols: []Symbol{{Symbol: "X", Offset: 2}}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := NewRouteResponse(models.Coordinates{X: 3, Y: 1}, models.East, route, spans, tt.err)
+			if resp.Error != nil {
+				assert.NotEmpty(t, resp.Error.Message)
+				assert.Equal(t, tt.err.Error(), resp.Error.Detail)
+				resp.Error.Message, resp.Error.Detail = "", ""
+			}
+			assert.Equal(t, tt.expected, resp)
+		})
+	}
+}
+
+func TestErrorCode(t *testing.T) {
+	tests := []struct {
+		err      error
+		expected string
+	}{
+		{err: models.SymbolErrors{{Offset: 0, Symbol: 'X'}}, expected: CodeInvalidSymbol},
+		{err: fmt.Errorf("%w: unclosed '('", models.ErrRouteSyntax), expected: CodeSyntax},
+		{err: fmt.Errorf("%w for the safe optimizer: 5 commands", models.ErrRouteTooLong), expected: CodeRouteTooLong},
+		{err: &models.RouteError{Err: models.ErrOutOfBounds}, expected: CodeOutOfBounds},
+		{err: &models.RouteError{Err: models.ErrObstacle}, expected: CodeObstacle},
+		{err: &models.RouteError{Err: models.ErrRoverCollision}, expected: CodeRoverCollision},
+		{err: &models.RouteError{Err: models.ErrBatteryDepleted}, expected: CodeBatteryDepleted},
+		{err: errors.New("disk failure"), expected: CodeInternal},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expected, func(t *testing.T) {
+			assert.Equal(t, tt.expected, ErrorCode(tt.err))
+		})
+	}
+}
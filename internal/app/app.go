@@ -4,54 +4,227 @@ import (
 	"errors"
 	"fmt"
 	"github.com/eiannone/keyboard"
+	"io"
 	"mars-rover/internal/models"
+	"mars-rover/internal/optimization"
+	"mars-rover/internal/plateau"
+	"mars-rover/internal/routelang"
+	"strings"
 )
 
+// streamBatch сколько движений CalculateStream передаёт марсоходу за раз
+const streamBatch = 1024
+
+// maxShownSymbols сколько недопустимых символов маршрута HandleError показывает с фрагментом маршрута
+const maxShownSymbols = 5
+
 type Rover interface {
-	PerformRoute(route []models.Move)
+	PerformRoute(route []models.Move) error
 	GetCurrentPosition() models.Coordinates
 	GetCurrentDirection() models.Direction
-	Move(steps int)
-	Rotate(steps int)
+	Move(steps int) error
+	Rotate(steps int) error
+	SetPlateau(p *plateau.Plateau)
+}
+
+// SpanRover марсоход, который передаёт в трассировку диапазоны исходных команд каждого движения
+type SpanRover interface {
+	PerformRouteWithSpans(route []models.Move, spans []models.Span) error
 }
 
 type Optimizer interface {
 	OptimizeRoute(commands string) ([]models.Move, error)
 }
 
+// StartAwareOptimizer оптимизатор, которому для работы нужно текущее состояние марсохода
+type StartAwareOptimizer interface {
+	SetStart(pos models.Coordinates, dir models.Direction)
+}
+
+// SpanOptimizer оптимизатор, который сообщает, из каких команд исходного маршрута получено каждое движение
+type SpanOptimizer interface {
+	OptimizeRouteWithSpans(commands string) ([]models.Move, []models.Span, error)
+}
+
 type App struct {
 	Rover     Rover
 	Optimizer Optimizer
+	// Plateau плато с границами и препятствиями, nil означает бесконечную плоскость
+	Plateau *plateau.Plateau
+
+	route []models.Move
+	spans []models.Span
 }
 
-func NewApp(rover Rover, optimizer Optimizer) *App {
-	return &App{
+type Option func(*App)
+
+// WithPlateau задаёт плато, по которому ездит марсоход
+func WithPlateau(p *plateau.Plateau) Option {
+	return func(a *App) {
+		a.Plateau = p
+	}
+}
+
+func NewApp(rover Rover, optimizer Optimizer, opts ...Option) *App {
+	a := &App{
 		Rover:     rover,
 		Optimizer: optimizer,
 	}
+	for _, opt := range opts {
+		opt(a)
+	}
+
+	if a.Plateau != nil {
+		a.Rover.SetPlateau(a.Plateau)
+	}
+	return a
 }
 
 func (a *App) CalculateRoute(commands string) (models.Coordinates, models.Direction, error) {
-	route, err := a.Optimizer.OptimizeRoute(commands)
+	if o, ok := a.Optimizer.(StartAwareOptimizer); ok {
+		o.SetStart(a.Rover.GetCurrentPosition(), a.Rover.GetCurrentDirection())
+	}
+
+	var (
+		route []models.Move
+		err   error
+	)
+	a.route, a.spans = nil, nil
+	if o, ok := a.Optimizer.(SpanOptimizer); ok {
+		route, a.spans, err = o.OptimizeRouteWithSpans(commands)
+	} else {
+		route, err = a.Optimizer.OptimizeRoute(commands)
+	}
+	a.route = route
 	if err != nil {
 		return models.Coordinates{}, "", err
 	}
 
-	a.Rover.PerformRoute(route)
-	return a.Rover.GetCurrentPosition(), a.Rover.GetCurrentDirection(), nil
+	err = a.perform(route, a.spans)
+	LocateError(err, commands, route, a.spans)
+	return a.Rover.GetCurrentPosition(), a.Rover.GetCurrentDirection(), err
 }
 
+// CalculateStream выполняет маршрут, читая команды из r: движения схлопываются как в optimization.OptimizeStream
+// и передаются марсоходу пачками, поэтому память не зависит от длины маршрута. Ошибка в середине потока
+// останавливает марсоход там, куда он успел доехать. Оптимизатор App не используется, Route и Spans после вызова
+// пустые. Индекс в models.RouteError - смещение команды от начала потока: точное, если r реализует io.ReaderAt
+// и читается с начала, иначе начало диапазона команд, из которых получено движение
+func (a *App) CalculateStream(r io.Reader) (models.Coordinates, models.Direction, error) {
+	a.route, a.spans = nil, nil
+	batch := make([]models.Move, 0, streamBatch)
+	spans := make([]models.Span, 0, streamBatch)
+
+	perform := func() error {
+		err := a.perform(batch, spans)
+		var routeErr *models.RouteError
+		if errors.As(err, &routeErr) && routeErr.Move >= 0 && routeErr.Move < len(spans) {
+			span := spans[routeErr.Move]
+			routeErr.Locate(batch[routeErr.Move], span, streamCommands(r, span))
+		}
+		batch, spans = batch[:0], spans[:0]
+		return err
+	}
+
+	err := optimization.OptimizeStream(r, func(m models.Move, span models.Span) error {
+		batch = append(batch, m)
+		spans = append(spans, span)
+		if len(batch) < streamBatch {
+			return nil
+		}
+		return perform()
+	})
+	// движения до ошибки во входных данных выполняются, ошибка марсохода важнее
+	if len(batch) > 0 {
+		if performErr := perform(); performErr != nil {
+			err = performErr
+		}
+	}
+	return a.Rover.GetCurrentPosition(), a.Rover.GetCurrentDirection(), err
+}
+
+// perform выполняет маршрут, передавая марсоходу диапазоны исходных команд, если он их принимает
+func (a *App) perform(route []models.Move, spans []models.Span) error {
+	if r, ok := a.Rover.(SpanRover); ok && spans != nil {
+		return r.PerformRouteWithSpans(route, spans)
+	}
+	return a.Rover.PerformRoute(route)
+}
+
+// LocateError переводит ошибку err движения маршрута route, полученного из команд commands, в исходный маршрут
+// по диапазонам движений spans. Без диапазонов ошибка не меняется
+func LocateError(err error, commands string, route []models.Move, spans []models.Span) {
+	var routeErr *models.RouteError
+	if !errors.As(err, &routeErr) || routeErr.Move < 0 || routeErr.Move >= len(spans) {
+		return
+	}
+	var source models.Commands
+	// маршрут уже разобран оптимизатором, ошибки разбора здесь не бывает
+	if program, err := routelang.Parse(commands); err == nil {
+		source = program.Walk
+	}
+	routeErr.Locate(route[routeErr.Move], spans[routeErr.Move], source)
+}
+
+// streamCommands перебирает команды потока r из диапазона span, если поток позволяет перечитать их, иначе nil
+func streamCommands(r io.Reader, span models.Span) models.Commands {
+	readerAt, ok := r.(io.ReaderAt)
+	if !ok {
+		return nil
+	}
+	return func(yield func(command rune, count int, s models.Span) bool) {
+		section := io.NewSectionReader(readerAt, int64(span.Start), int64(span.End-span.Start))
+		_ = optimization.ScanStream(section, func(command rune, count int, s models.Span) bool {
+			return yield(command, count, models.Span{Start: span.Start + s.Start, End: span.Start + s.End})
+		})
+	}
+}
+
+// Route возвращает оптимизированный маршрут, рассчитанный последним вызовом CalculateRoute
+func (a *App) Route() []models.Move {
+	return a.route
+}
+
+// Spans возвращает для каждого движения последнего рассчитанного маршрута диапазон исходных команд,
+// из которых оно получено, nil если оптимизатор не реализует SpanOptimizer
+func (a *App) Spans() []models.Span {
+	return a.spans
+}
+
+// InteractiveControl выполняет команды из input и пишет результат каждой в output. Кроме движений
+// поддерживаются отмена и повтор команд (undo, redo) и контрольные точки (checkpoint имя, restore имя)
 func (a *App) InteractiveControl(input <-chan string, output chan<- string) error {
+	history := NewHistory(a.Rover, a.Plateau)
+	index := -1
 	for command := range input {
-		switch command {
+		index++
+
+		var (
+			err    error
+			prefix string
+		)
+		name, arg, _ := strings.Cut(command, " ")
+		switch name {
 		case "up":
-			a.Rover.Move(1)
+			err = history.Move(1)
 		case "down":
-			a.Rover.Move(-1)
+			err = history.Move(-1)
 		case "right":
-			a.Rover.Rotate(-1)
+			err = history.Rotate(-1)
 		case "left":
-			a.Rover.Rotate(1)
+			err = history.Rotate(1)
+		case "undo":
+			err = history.Undo()
+			prefix = "Команда отменена. "
+		case "redo":
+			err = history.Redo()
+			prefix = "Команда повторена. "
+		case "checkpoint":
+			history.Checkpoint(arg)
+			prefix = fmt.Sprintf("Контрольная точка %s сохранена. ", arg)
+		case "restore":
+			err = history.Restore(arg)
+			prefix = fmt.Sprintf("Марсоход вернулся к контрольной точке %s. ", arg)
 		case "exit":
 			close(output)
 			return nil
@@ -62,7 +235,21 @@ func (a *App) InteractiveControl(input <-chan string, output chan<- string) erro
 
 		pos := a.Rover.GetCurrentPosition()
 		dir := a.Rover.GetCurrentDirection()
-		output <- fmt.Sprintf("Текущие координаты: (%d, %d), направление: %s", pos.X, pos.Y, dir)
+		switch {
+		case errors.Is(err, ErrNothingToUndo):
+			output <- "Нет команд для отмены"
+			continue
+		case errors.Is(err, ErrNothingToRedo):
+			output <- "Нет отменённых команд для повтора"
+			continue
+		case errors.Is(err, ErrUnknownCheckpoint):
+			output <- fmt.Sprintf("Контрольная точка %s не найдена", arg)
+			continue
+		case err != nil:
+			output <- HandleError(&models.RouteError{Index: index, Move: -1, Pos: pos, Direction: dir, Err: err})
+			continue
+		}
+		output <- fmt.Sprintf("%sТекущие координаты: (%d, %d), направление: %s", prefix, pos.X, pos.Y, dir)
 	}
 
 	return nil
@@ -75,8 +262,9 @@ func (a *App) CaptureInput(input chan<- string) error {
 	}
 	defer keyboard.Close()
 
+	checkpoints := 0
 	for {
-		_, key, err := keyboard.GetKey()
+		char, key, err := keyboard.GetKey()
 		if err != nil {
 			return fmt.Errorf("failed to get key: %w", err)
 		}
@@ -87,6 +275,23 @@ func (a *App) CaptureInput(input chan<- string) error {
 			break
 		}
 
+		switch {
+		case key == keyboard.KeyCtrlZ || char == 'u':
+			input <- "undo"
+			continue
+		case key == keyboard.KeyCtrlY || char == 'r':
+			input <- "redo"
+			continue
+		case char == 'c':
+			// контрольные точки с клавиатуры нумеруются по порядку, вернуться к точке можно клавишей с её номером
+			checkpoints++
+			input <- fmt.Sprintf("checkpoint %d", checkpoints)
+			continue
+		case char >= '1' && char <= '9':
+			input <- fmt.Sprintf("restore %c", char)
+			continue
+		}
+
 		switch key {
 		case keyboard.KeyArrowUp:
 			input <- "up"
@@ -105,16 +310,77 @@ func (a *App) CaptureInput(input chan<- string) error {
 }
 
 func (a *App) HandleCommands(commands string) (models.Coordinates, models.Direction, error) {
-	position, direction, err := a.CalculateRoute(commands)
-	if err != nil {
-		return models.Coordinates{}, "", err
-	}
-	return position, direction, nil
+	return a.CalculateRoute(commands)
 }
 
 func HandleError(err error) string {
+	var symbolErrs models.SymbolErrors
+	if errors.As(err, &symbolErrs) {
+		return handleSymbolErrors(symbolErrs)
+	}
 	if errors.Is(err, models.ErrIncorrectSymbol) {
 		return fmt.Sprintf("Некорректный путь: %v, путь должен состоять только из символов F, B, R, L", err)
 	}
+	if errors.Is(err, models.ErrRouteSyntax) {
+		return fmt.Sprintf("Некорректный синтаксис маршрута: %v", err)
+	}
+	if errors.Is(err, models.ErrRouteTooLong) {
+		return fmt.Sprintf("Маршрут слишком длинный для выбранной стратегии оптимизации: %v", err)
+	}
+	var routeErr *models.RouteError
+	if errors.As(err, &routeErr) && errors.Is(err, models.ErrOutOfBounds) {
+		return fmt.Sprintf("Марсоход упёрся в границу плато на команде %s. "+
+			"Марсоход остановился в точке (%d, %d), направление: %s",
+			commandAt(routeErr), routeErr.Pos.X, routeErr.Pos.Y, routeErr.Direction)
+	}
+	if errors.As(err, &routeErr) && errors.Is(err, models.ErrRoverCollision) {
+		return fmt.Sprintf("Марсоход остановился, чтобы не столкнуться с другим марсоходом, на команде %s. "+
+			"Последняя безопасная точка (%d, %d), направление: %s",
+			commandAt(routeErr), routeErr.Pos.X, routeErr.Pos.Y, routeErr.Direction)
+	}
+	if errors.As(err, &routeErr) && errors.Is(err, models.ErrObstacle) {
+		return fmt.Sprintf("Марсоход остановился перед препятствием на команде %s. "+
+			"Последняя безопасная точка (%d, %d), направление: %s",
+			commandAt(routeErr), routeErr.Pos.X, routeErr.Pos.Y, routeErr.Direction)
+	}
+	if errors.As(err, &routeErr) && errors.Is(err, models.ErrBatteryDepleted) {
+		return fmt.Sprintf("У марсохода разрядилась батарея на команде %s. "+
+			"Марсоход остановился в точке (%d, %d), направление: %s",
+			commandAt(routeErr), routeErr.Pos.X, routeErr.Pos.Y, routeErr.Direction)
+	}
 	return fmt.Sprintf("Ошибка: %v", err)
 }
+
+// commandAt описывает команду, на которой остановился марсоход: место в файле маршрута, смещение с исходными
+// командами или, если исходная команда неизвестна, номер движения оптимизированного маршрута
+func commandAt(routeErr *models.RouteError) string {
+	switch {
+	case routeErr.Location != "":
+		return "в " + routeErr.Location
+	case routeErr.Index < 0:
+		return fmt.Sprintf("оптимизированного маршрута с индексом %d", routeErr.Move)
+	case routeErr.Span == (models.Span{}):
+		return fmt.Sprintf("с индексом %d", routeErr.Index)
+	}
+	return fmt.Sprintf("с индексом %d (исходные команды [%d, %d))", routeErr.Index, routeErr.Span.Start, routeErr.Span.End)
+}
+
+// handleSymbolErrors выводит недопустимые символы маршрута с фрагментом маршрута и кареткой под каждым символом
+func handleSymbolErrors(errs models.SymbolErrors) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Некорректный путь, путь должен состоять только из символов F, B, R, L. Недопустимых символов: %d",
+		len(errs))
+	for i, symbolErr := range errs {
+		if i == maxShownSymbols {
+			fmt.Fprintf(&b, "\n  ... и ещё %d", len(errs)-i)
+			break
+		}
+		at := fmt.Sprintf("смещение %d", symbolErr.Offset)
+		if symbolErr.Location != "" {
+			at = symbolErr.Location
+		}
+		fmt.Fprintf(&b, "\n  %s\n  %s^ символ %q, %s",
+			symbolErr.Context, strings.Repeat(" ", symbolErr.Caret), symbolErr.Symbol, at)
+	}
+	return b.String()
+}
@@ -2,8 +2,13 @@ package app
 
 import (
 	"errors"
+	"fmt"
 	"mars-rover/internal/mocks"
 	"mars-rover/internal/models"
+	"mars-rover/internal/optimization"
+	"mars-rover/internal/plateau"
+	"mars-rover/internal/rover"
+	"strings"
 	"testing"
 
 	"github.com/golang/mock/gomock"
@@ -26,6 +31,7 @@ func TestCalculateRoute(t *testing.T) {
 		expectedPosition  models.Coordinates
 		expectedDirection models.Direction
 		optimizeError     error
+		routeError        error
 		expectError       bool
 	}{
 		{
@@ -50,6 +56,17 @@ func TestCalculateRoute(t *testing.T) {
 			optimizeError:     errors.New("optimization error"),
 			expectError:       true,
 		},
+		{
+			name:     "Route stopped at plateau edge",
+			commands: "FFFFF",
+			expectedRoute: []models.Move{
+				{Type: models.Movement, Value: 5},
+			},
+			expectedPosition:  models.Coordinates{X: 1, Y: 4},
+			expectedDirection: models.North,
+			routeError:        &models.RouteError{Index: 0, Pos: models.Coordinates{X: 1, Y: 4}, Err: models.ErrOutOfBounds},
+			expectError:       true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -57,7 +74,7 @@ func TestCalculateRoute(t *testing.T) {
 			mockOptimizer.EXPECT().OptimizeRoute(tt.commands).Return(tt.expectedRoute, tt.optimizeError)
 
 			if tt.optimizeError == nil {
-				mockRover.EXPECT().PerformRoute(tt.expectedRoute)
+				mockRover.EXPECT().PerformRoute(tt.expectedRoute).Return(tt.routeError)
 				mockRover.EXPECT().GetCurrentPosition().Return(tt.expectedPosition)
 				mockRover.EXPECT().GetCurrentDirection().Return(tt.expectedDirection)
 			}
@@ -66,6 +83,11 @@ func TestCalculateRoute(t *testing.T) {
 
 			if tt.expectError {
 				require.Error(t, err)
+				if tt.routeError != nil {
+					assert.ErrorIs(t, err, models.ErrOutOfBounds)
+					assert.Equal(t, tt.expectedPosition, position)
+					assert.Equal(t, tt.expectedDirection, direction)
+				}
 			} else {
 				require.NoError(t, err)
 				assert.Equal(t, tt.expectedPosition, position)
@@ -75,6 +97,334 @@ func TestCalculateRoute(t *testing.T) {
 	}
 }
 
+func TestCalculateRouteWithStartAwareOptimizer(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	p := &plateau.Plateau{Obstacles: plateau.NewObstacles(models.Coordinates{X: 5, Y: 8})}
+	optimizer := optimization.NewSafeOptimizer(p)
+	mockRover := mocks.NewMockRover(ctrl)
+	mockRover.EXPECT().SetPlateau(p)
+	app := NewApp(mockRover, optimizer, WithPlateau(p))
+
+	gomock.InOrder(
+		mockRover.EXPECT().GetCurrentPosition().Return(models.Coordinates{X: 5, Y: 5}),
+		mockRover.EXPECT().GetCurrentDirection().Return(models.North),
+		mockRover.EXPECT().PerformRoute([]models.Move{
+			{Type: models.Movement, Value: 3},
+			{Type: models.Movement, Value: -3},
+		}),
+		mockRover.EXPECT().GetCurrentPosition().Return(models.Coordinates{X: 5, Y: 7}),
+		mockRover.EXPECT().GetCurrentDirection().Return(models.North),
+	)
+
+	_, _, err := app.CalculateRoute("FFFBBB")
+	require.NoError(t, err)
+	assert.Equal(t, models.Coordinates{X: 5, Y: 5}, optimizer.Start)
+}
+
+func TestCalculateRouteWithSpanOptimizer(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRover := mocks.NewMockRover(ctrl)
+	app := NewApp(mockRover, optimization.NewOptimizer())
+
+	routeErr := &models.RouteError{Index: -1, Move: 1, Pos: models.Coordinates{X: 1, Y: 3}, Err: models.ErrObstacle}
+	gomock.InOrder(
+		mockRover.EXPECT().PerformRoute([]models.Move{
+			{Type: models.Rotation, Value: -1},
+			{Type: models.Movement, Value: 1},
+		}).Return(routeErr),
+		mockRover.EXPECT().GetCurrentPosition().Return(models.Coordinates{X: 1, Y: 3}),
+		mockRover.EXPECT().GetCurrentDirection().Return(models.East),
+	)
+
+	_, _, err := app.CalculateRoute("LRRFFFBB")
+	require.ErrorIs(t, err, models.ErrObstacle)
+	assert.Equal(t, []models.Move{{Type: models.Rotation, Value: -1}, {Type: models.Movement, Value: 1}}, app.Route())
+	assert.Equal(t, []models.Span{{Start: 0, End: 3}, {Start: 3, End: 8}}, app.Spans())
+	assert.Equal(t, models.Span{Start: 3, End: 8}, routeErr.Span)
+}
+
+func TestCalculateRouteTraceSpans(t *testing.T) {
+	trace := rover.NewTrace()
+	app := NewApp(rover.NewRover(rover.WithRecorder(trace)), optimization.NewOptimizer())
+
+	_, _, err := app.CalculateRoute("LRFFFBBR")
+	require.NoError(t, err)
+
+	require.Len(t, trace.Steps, 2)
+	assert.Equal(t, app.Spans()[0], trace.Steps[0].Span)
+	assert.Equal(t, models.Span{Start: 2, End: 7}, trace.Steps[0].Span)
+	assert.Equal(t, models.Span{Start: 7, End: 8}, trace.Steps[1].Span)
+}
+
+func TestCalculateRouteWithBattery(t *testing.T) {
+	r := rover.NewRover(rover.WithBattery(rover.Costs{Forward: 1, Turn: 1}, 4))
+	app := NewApp(r, optimization.NewOptimizer())
+
+	position, direction, err := app.CalculateRoute("FFRFFF")
+
+	var routeErr *models.RouteError
+	require.ErrorAs(t, err, &routeErr)
+	assert.ErrorIs(t, err, models.ErrBatteryDepleted)
+	// заряда хватило на первую F отрезка FFF, батарея разрядилась на второй
+	assert.Equal(t, 4, routeErr.Index)
+	assert.Equal(t, models.Span{Start: 3, End: 6}, routeErr.Span)
+	assert.Equal(t, models.Coordinates{X: 2, Y: 3}, position)
+	assert.Equal(t, models.East, direction)
+}
+
+// TestCalculateRouteIdleCost проверяет, что работа систем списывается за клетки и повороты, которые марсоход
+// выполнил после оптимизации: слияние одинаковых команд расход не меняет, сокращённые команды заряд не тратят
+func TestCalculateRouteIdleCost(t *testing.T) {
+	tests := []struct {
+		route       string
+		optimized   int
+		passthrough int
+	}{
+		{route: "FFFF", optimized: 96, passthrough: 96},
+		{route: "4F", optimized: 96, passthrough: 96},
+		{route: "FFFBB", optimized: 99, passthrough: 95},
+		{route: "FB", optimized: 100, passthrough: 98},
+		{route: "LLLL", optimized: 100, passthrough: 96},
+		{route: "LLLLL", optimized: 99, passthrough: 95},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.route, func(t *testing.T) {
+			for optimizer, expected := range map[Optimizer]int{
+				optimization.NewOptimizer():            tt.optimized,
+				optimization.NewPassthroughOptimizer(): tt.passthrough,
+			} {
+				r := rover.NewRover(rover.WithBattery(rover.Costs{Idle: 1}, 100))
+				_, _, err := NewApp(r, optimizer).CalculateRoute(tt.route)
+				require.NoError(t, err)
+				assert.Equal(t, expected, r.Battery.Charge, "%T", optimizer)
+			}
+		})
+	}
+}
+
+func TestCalculateRouteReportsSourceCommand(t *testing.T) {
+	tests := []struct {
+		name     string
+		route    string
+		obstacle models.Coordinates
+		expected int
+		span     models.Span
+	}{
+		{
+			// RL схлопывается в ничто, FFFFF в одно движение: марсоход проходит две клетки и останавливается на третьей F
+			name: "Collapsed movement", route: "RLFFFFF", obstacle: models.Coordinates{X: 1, Y: 4},
+			expected: 4, span: models.Span{Start: 2, End: 7},
+		},
+		{
+			// B4F схлопывается в 3F: после B марсоход доехал бы до препятствия командой 4F
+			name: "Backward command before repeat count", route: "B4F", obstacle: models.Coordinates{X: 1, Y: 2},
+			expected: 1, span: models.Span{Start: 0, End: 3},
+		},
+		{
+			name: "Commands cancel out", route: "FFFBB", obstacle: models.Coordinates{X: 1, Y: 2},
+			expected: 0, span: models.Span{Start: 0, End: 5},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &plateau.Plateau{Obstacles: plateau.NewObstacles(tt.obstacle)}
+			app := NewApp(rover.NewRover(), optimization.NewOptimizer(), WithPlateau(p))
+
+			_, _, err := app.CalculateRoute(tt.route)
+
+			var routeErr *models.RouteError
+			require.ErrorAs(t, err, &routeErr)
+			assert.Equal(t, tt.expected, routeErr.Index)
+			assert.Equal(t, 0, routeErr.Move)
+			assert.Equal(t, tt.span, routeErr.Span)
+		})
+	}
+}
+
+func TestCalculateStream(t *testing.T) {
+	// 1500 раз "FL" обходит квадрат 1x1, после этого марсоход упирается в препятствие в (1, 2)
+	route := strings.Repeat("FL", 1500) + "\nFF"
+	p := &plateau.Plateau{Obstacles: plateau.NewObstacles(models.Coordinates{X: 1, Y: 3})}
+	app := NewApp(rover.NewRover(), optimization.NewOptimizer(), WithPlateau(p))
+
+	position, direction, err := app.CalculateStream(strings.NewReader(route))
+
+	var routeErr *models.RouteError
+	require.ErrorAs(t, err, &routeErr)
+	assert.ErrorIs(t, err, models.ErrObstacle)
+	assert.Equal(t, 3002, routeErr.Index)
+	assert.Equal(t, models.Span{Start: 3001, End: 3003}, routeErr.Span)
+	assert.Equal(t, models.Coordinates{X: 1, Y: 2}, position)
+	assert.Equal(t, models.North, direction)
+}
+
+func TestCalculateStreamSyntaxError(t *testing.T) {
+	r := rover.NewRover()
+	app := NewApp(r, optimization.NewOptimizer())
+
+	// команды до недопустимого символа выполняются
+	position, _, err := app.CalculateStream(strings.NewReader("FF\nFX"))
+	assert.ErrorIs(t, err, models.ErrIncorrectSymbol)
+	assert.Equal(t, models.Coordinates{X: 1, Y: 4}, position)
+}
+
+// benchmarkCommands маршрут из 1<<20 команд для сравнения выполнения строки и потока
+func benchmarkCommands() string {
+	return strings.Repeat("FFBLFRRL", 1<<17)
+}
+
+func BenchmarkCalculateRoute(b *testing.B) {
+	commands := benchmarkCommands()
+	b.ReportAllocs()
+	b.SetBytes(int64(len(commands)))
+	for i := 0; i < b.N; i++ {
+		app := NewApp(rover.NewRover(), optimization.NewOptimizer())
+		if _, _, err := app.CalculateRoute(commands); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkCalculateStream(b *testing.B) {
+	commands := benchmarkCommands()
+	b.ReportAllocs()
+	b.SetBytes(int64(len(commands)))
+	for i := 0; i < b.N; i++ {
+		app := NewApp(rover.NewRover(), optimization.NewOptimizer())
+		if _, _, err := app.CalculateStream(strings.NewReader(commands)); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestNewAppWithPlateau(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	p := &plateau.Plateau{Obstacles: plateau.NewObstacles(models.Coordinates{X: 1, Y: 3})}
+	mockRover := mocks.NewMockRover(ctrl)
+	mockRover.EXPECT().SetPlateau(p)
+
+	app := NewApp(mockRover, mocks.NewMockOptimizer(ctrl), WithPlateau(p))
+	assert.Equal(t, p, app.Plateau)
+}
+
+func TestHandleError(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		expected string
+	}{
+		{
+			name: "Obstacle",
+			err: &models.RouteError{
+				Index: 2, Pos: models.Coordinates{X: 1, Y: 2}, Direction: models.North, Err: models.ErrObstacle,
+			},
+			expected: "Марсоход остановился перед препятствием на команде с индексом 2. " +
+				"Последняя безопасная точка (1, 2), направление: N",
+		},
+		{
+			name: "Obstacle with source commands",
+			err: &models.RouteError{
+				Index: 1, Pos: models.Coordinates{X: 1, Y: 3}, Direction: models.North,
+				Span: models.Span{Start: 2, End: 7}, Err: models.ErrObstacle,
+			},
+			expected: "Марсоход остановился перед препятствием на команде с индексом 1 (исходные команды [2, 7)). " +
+				"Последняя безопасная точка (1, 3), направление: N",
+		},
+		{
+			name: "Obstacle in route file",
+			err: &models.RouteError{
+				Index: 5, Location: "patrol.route:3:2", Pos: models.Coordinates{X: 1, Y: 3}, Direction: models.North,
+				Span: models.Span{Start: 2, End: 7}, Err: models.ErrObstacle,
+			},
+			expected: "Марсоход остановился перед препятствием на команде в patrol.route:3:2. " +
+				"Последняя безопасная точка (1, 3), направление: N",
+		},
+		{
+			name: "Obstacle without source command",
+			err: &models.RouteError{
+				Index: -1, Move: 4, Pos: models.Coordinates{X: 1, Y: 3}, Direction: models.North, Err: models.ErrObstacle,
+			},
+			expected: "Марсоход остановился перед препятствием на команде оптимизированного маршрута с индексом 4. " +
+				"Последняя безопасная точка (1, 3), направление: N",
+		},
+		{
+			name: "Battery depleted",
+			err: &models.RouteError{
+				Index: 3, Pos: models.Coordinates{X: 4, Y: 1}, Direction: models.East, Err: models.ErrBatteryDepleted,
+			},
+			expected: "У марсохода разрядилась батарея на команде с индексом 3. " +
+				"Марсоход остановился в точке (4, 1), направление: E",
+		},
+		{
+			name: "Rover collision",
+			err: &models.RouteError{
+				Index: 0, Pos: models.Coordinates{X: 3, Y: 0}, Direction: models.West, Err: models.ErrRoverCollision,
+			},
+			expected: "Марсоход остановился, чтобы не столкнуться с другим марсоходом, на команде с индексом 0. " +
+				"Последняя безопасная точка (3, 0), направление: W",
+		},
+		{
+			name: "Invalid symbols in console route",
+			err: models.SymbolErrors{
+				{Offset: 2, Symbol: 'X', Context: "FFXFЖ", Caret: 2},
+				{Offset: 4, Symbol: 'Ж', Context: "FFXFЖ", Caret: 4},
+			},
+			expected: "Некорректный путь, путь должен состоять только из символов F, B, R, L. Недопустимых символов: 2\n" +
+				"  FFXFЖ\n" +
+				"    ^ символ 'X', смещение 2\n" +
+				"  FFXFЖ\n" +
+				"      ^ символ 'Ж', смещение 4",
+		},
+		{
+			name: "Invalid symbol in route file",
+			err: models.SymbolErrors{
+				{Offset: 7, Symbol: 'X', Location: "route.txt:3:3", Context: " FX # вперёд", Caret: 2},
+			},
+			expected: "Некорректный путь, путь должен состоять только из символов F, B, R, L. Недопустимых символов: 1\n" +
+				"   FX # вперёд\n" +
+				"    ^ символ 'X', route.txt:3:3",
+		},
+		{
+			name: "Too many invalid symbols",
+			err: models.SymbolErrors{
+				{Symbol: 'a'}, {Offset: 1, Symbol: 'b'}, {Offset: 2, Symbol: 'c'},
+				{Offset: 3, Symbol: 'd'}, {Offset: 4, Symbol: 'e'}, {Offset: 5, Symbol: 'f'}, {Offset: 6, Symbol: 'g'},
+			},
+			expected: "Некорректный путь, путь должен состоять только из символов F, B, R, L. Недопустимых символов: 7\n" +
+				"  \n  ^ символ 'a', смещение 0\n" +
+				"  \n  ^ символ 'b', смещение 1\n" +
+				"  \n  ^ символ 'c', смещение 2\n" +
+				"  \n  ^ символ 'd', смещение 3\n" +
+				"  \n  ^ символ 'e', смещение 4\n" +
+				"  ... и ещё 2",
+		},
+		{
+			name:     "Route syntax",
+			err:      fmt.Errorf("%w: unclosed '(' at offset 3", models.ErrRouteSyntax),
+			expected: "Некорректный синтаксис маршрута: syntax error: malformed route: unclosed '(' at offset 3",
+		},
+		{
+			name:     "Unknown error",
+			err:      errors.New("boom"),
+			expected: "Ошибка: boom",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, HandleError(tt.err))
+		})
+	}
+}
+
 func TestInteractiveControl(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
@@ -139,3 +489,99 @@ func TestInteractiveControl(t *testing.T) {
 		})
 	}
 }
+
+func TestInteractiveControlBattery(t *testing.T) {
+	app := NewApp(rover.NewRover(rover.WithBattery(rover.Costs{Forward: 1, Turn: 1}, 2)), nil)
+
+	input := make(chan string)
+	output := make(chan string)
+
+	go func() {
+		err := app.InteractiveControl(input, output)
+		require.NoError(t, err)
+	}()
+
+	steps := []struct {
+		command  string
+		expected string
+	}{
+		{"up", "Текущие координаты: (1, 2), направление: N"},
+		{"left", "Текущие координаты: (1, 2), направление: W"},
+		{"right", "У марсохода разрядилась батарея на команде с индексом 2. Марсоход остановился в точке (1, 2), направление: W"},
+		{"undo", "У марсохода разрядилась батарея на команде с индексом 3. Марсоход остановился в точке (1, 2), направление: W"},
+	}
+	for _, step := range steps {
+		input <- step.command
+		assert.Equal(t, step.expected, <-output, step.command)
+	}
+	input <- "exit"
+	_, ok := <-output
+	assert.False(t, ok)
+}
+
+func TestInteractiveControlHistory(t *testing.T) {
+	app := NewApp(rover.NewRover(), nil)
+
+	input := make(chan string)
+	output := make(chan string)
+
+	go func() {
+		err := app.InteractiveControl(input, output)
+		require.NoError(t, err)
+	}()
+
+	steps := []struct {
+		command  string
+		expected string
+	}{
+		{"undo", "Нет команд для отмены"},
+		{"checkpoint base", "Контрольная точка base сохранена. Текущие координаты: (1, 1), направление: N"},
+		{"up", "Текущие координаты: (1, 2), направление: N"},
+		{"right", "Текущие координаты: (1, 2), направление: E"},
+		{"undo", "Команда отменена. Текущие координаты: (1, 2), направление: N"},
+		{"redo", "Команда повторена. Текущие координаты: (1, 2), направление: E"},
+		{"redo", "Нет отменённых команд для повтора"},
+		{"up", "Текущие координаты: (2, 2), направление: E"},
+		{"restore base", "Марсоход вернулся к контрольной точке base. Текущие координаты: (1, 1), направление: N"},
+		{"restore top", "Контрольная точка top не найдена"},
+	}
+	for _, step := range steps {
+		input <- step.command
+		assert.Equal(t, step.expected, <-output, step.command)
+	}
+	input <- "exit"
+	_, ok := <-output
+	assert.False(t, ok)
+}
+
+func TestInteractiveControlBoundary(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRover := mocks.NewMockRover(ctrl)
+	app := NewApp(mockRover, nil)
+
+	input := make(chan string)
+	output := make(chan string)
+
+	go func() {
+		err := app.InteractiveControl(input, output)
+		require.NoError(t, err)
+	}()
+
+	gomock.InOrder(
+		mockRover.EXPECT().Move(1).Return(nil),
+		mockRover.EXPECT().Move(1).Return(models.ErrOutOfBounds),
+	)
+	mockRover.EXPECT().GetCurrentPosition().AnyTimes().Return(models.Coordinates{X: 1, Y: 2})
+	mockRover.EXPECT().GetCurrentDirection().AnyTimes().Return(models.North)
+
+	input <- "up"
+	assert.Equal(t, "Текущие координаты: (1, 2), направление: N", <-output)
+	input <- "up"
+	assert.Equal(t, "Марсоход упёрся в границу плато на команде с индексом 1. "+
+		"Марсоход остановился в точке (1, 2), направление: N", <-output)
+	input <- "exit"
+	_, ok := <-output
+	assert.False(t, ok)
+}
@@ -0,0 +1,139 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"mars-rover/internal/models"
+	"mars-rover/internal/plateau"
+)
+
+var (
+	ErrNothingToUndo     = errors.New("history error: nothing to undo")
+	ErrNothingToRedo     = errors.New("history error: nothing to redo")
+	ErrUnknownCheckpoint = errors.New("history error: unknown checkpoint")
+)
+
+// History история команд марсохода с отменой, повтором и именованными контрольными точками.
+// Хранятся команды, а не положения: отмена выполняет обратную команду через интерфейс Rover,
+// поэтому история работает с любой его реализацией, в том числе с моками. В историю попадают
+// только команды, которые марсоход выполнил без ошибки
+type History struct {
+	rover Rover
+	// plateau плато, по которому ездит марсоход, nil для бесконечной плоскости
+	plateau *plateau.Plateau
+	// commands выполненные команды, commands[:cursor] применены, commands[cursor:] можно повторить
+	commands []models.Move
+	cursor   int
+	// checkpoints для каждой контрольной точки количество применённых команд
+	checkpoints map[string]int
+}
+
+// NewHistory создаёт историю команд марсохода rover, который ездит по плато p, nil для бесконечной плоскости
+func NewHistory(rover Rover, p *plateau.Plateau) *History {
+	return &History{rover: rover, plateau: p, checkpoints: map[string]int{}}
+}
+
+// Move перемещает марсоход и запоминает команду. Новая команда отменяет возможность повтора
+// и удаляет контрольные точки, поставленные после текущего положения в истории
+func (h *History) Move(steps int) error {
+	clamp := h.plateau != nil && h.plateau.Edge == plateau.EdgeClamp
+	var from models.Coordinates
+	if clamp {
+		from = h.rover.GetCurrentPosition()
+	}
+	if err := h.rover.Move(steps); err != nil {
+		return err
+	}
+	// у края плато в режиме clamp марсоход проезжает меньше клеток, чем в команде, или остаётся на месте:
+	// запоминается пройденное расстояние, чтобы отмена вернула марсоход в ту клетку, откуда он выехал
+	if clamp {
+		to := h.rover.GetCurrentPosition()
+		moved := max(to.X-from.X, from.X-to.X) + max(to.Y-from.Y, from.Y-to.Y)
+		if steps < 0 {
+			moved = -moved
+		}
+		if moved == 0 {
+			return nil
+		}
+		steps = moved
+	}
+	h.record(models.Move{Type: models.Movement, Value: steps})
+	return nil
+}
+
+// Rotate поворачивает марсоход и запоминает команду
+func (h *History) Rotate(steps int) error {
+	if err := h.rover.Rotate(steps); err != nil {
+		return err
+	}
+	h.record(models.Move{Type: models.Rotation, Value: steps})
+	return nil
+}
+
+func (h *History) record(m models.Move) {
+	for name, at := range h.checkpoints {
+		if at > h.cursor {
+			delete(h.checkpoints, name)
+		}
+	}
+	h.commands = append(h.commands[:h.cursor], m)
+	h.cursor++
+}
+
+// Undo отменяет последнюю применённую команду обратной командой. Если марсоход не смог выполнить
+// обратную команду, история не меняется
+func (h *History) Undo() error {
+	if h.cursor == 0 {
+		return ErrNothingToUndo
+	}
+	m := h.commands[h.cursor-1]
+	if err := h.apply(models.Move{Type: m.Type, Value: -m.Value}); err != nil {
+		return err
+	}
+	h.cursor--
+	return nil
+}
+
+// Redo повторяет последнюю отменённую команду
+func (h *History) Redo() error {
+	if h.cursor == len(h.commands) {
+		return ErrNothingToRedo
+	}
+	if err := h.apply(h.commands[h.cursor]); err != nil {
+		return err
+	}
+	h.cursor++
+	return nil
+}
+
+// Checkpoint ставит контрольную точку name в текущем положении истории, точка с тем же именем перезаписывается
+func (h *History) Checkpoint(name string) {
+	h.checkpoints[name] = h.cursor
+}
+
+// Restore возвращает марсоход к контрольной точке name: отменяет команды, выполненные после неё,
+// или повторяет отменённые команды, если точка поставлена позже текущего положения в истории
+func (h *History) Restore(name string) error {
+	at, ok := h.checkpoints[name]
+	if !ok {
+		return fmt.Errorf("%w: %q", ErrUnknownCheckpoint, name)
+	}
+	for h.cursor > at {
+		if err := h.Undo(); err != nil {
+			return err
+		}
+	}
+	for h.cursor < at {
+		if err := h.Redo(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (h *History) apply(m models.Move) error {
+	if m.Type == models.Rotation {
+		return h.rover.Rotate(m.Value)
+	}
+	return h.rover.Move(m.Value)
+}
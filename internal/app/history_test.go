@@ -0,0 +1,117 @@
+package app
+
+import (
+	"mars-rover/internal/mocks"
+	"mars-rover/internal/models"
+	"mars-rover/internal/plateau"
+	"mars-rover/internal/rover"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHistory_UndoRedo(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRover := mocks.NewMockRover(ctrl)
+	gomock.InOrder(
+		mockRover.EXPECT().Move(1).Return(nil),
+		mockRover.EXPECT().Rotate(-1),
+		// отмена выполняет обратные команды в обратном порядке
+		mockRover.EXPECT().Rotate(1),
+		mockRover.EXPECT().Move(-1).Return(nil),
+		mockRover.EXPECT().Move(1).Return(nil),
+	)
+
+	h := NewHistory(mockRover, nil)
+	require.NoError(t, h.Move(1))
+	h.Rotate(-1)
+	require.NoError(t, h.Undo())
+	require.NoError(t, h.Undo())
+	assert.ErrorIs(t, h.Undo(), ErrNothingToUndo)
+	require.NoError(t, h.Redo())
+}
+
+func TestHistory_NewCommandDropsRedo(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRover := mocks.NewMockRover(ctrl)
+	mockRover.EXPECT().Move(gomock.Any()).AnyTimes().Return(nil)
+	mockRover.EXPECT().Rotate(gomock.Any()).AnyTimes()
+
+	h := NewHistory(mockRover, nil)
+	require.NoError(t, h.Move(1))
+	h.Checkpoint("after-move")
+	require.NoError(t, h.Move(1))
+	h.Checkpoint("after-two")
+	require.NoError(t, h.Undo())
+	require.NoError(t, h.Undo())
+
+	h.Rotate(1)
+	assert.ErrorIs(t, h.Redo(), ErrNothingToRedo)
+	// точки, поставленные после отменённых команд, больше не на пути марсохода
+	assert.ErrorIs(t, h.Restore("after-two"), ErrUnknownCheckpoint)
+	assert.ErrorIs(t, h.Restore("after-move"), ErrUnknownCheckpoint)
+}
+
+func TestHistory_FailedCommandIsNotRecorded(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRover := mocks.NewMockRover(ctrl)
+	mockRover.EXPECT().Move(1).Return(models.ErrObstacle)
+
+	h := NewHistory(mockRover, nil)
+	assert.ErrorIs(t, h.Move(1), models.ErrObstacle)
+	assert.ErrorIs(t, h.Undo(), ErrNothingToUndo)
+}
+
+func TestHistory_Restore(t *testing.T) {
+	p := &plateau.Plateau{Width: 5, Height: 5, Edge: plateau.EdgeWrap}
+	r := rover.NewRover(rover.WithPlateau(p))
+	h := NewHistory(r, p)
+
+	h.Checkpoint("start")
+	for i := 0; i < 4; i++ {
+		require.NoError(t, h.Move(1))
+	}
+	h.Rotate(-1)
+	require.NoError(t, h.Move(2))
+	h.Checkpoint("end")
+	assert.Equal(t, models.Coordinates{X: 3, Y: 0}, r.GetCurrentPosition())
+
+	require.NoError(t, h.Restore("start"))
+	assert.Equal(t, models.Coordinates{X: 1, Y: 1}, r.GetCurrentPosition())
+	assert.Equal(t, models.North, r.GetCurrentDirection())
+
+	// к точке, поставленной позже, марсоход возвращается повтором отменённых команд
+	require.NoError(t, h.Restore("end"))
+	assert.Equal(t, models.Coordinates{X: 3, Y: 0}, r.GetCurrentPosition())
+	assert.Equal(t, models.East, r.GetCurrentDirection())
+
+	assert.ErrorIs(t, h.Restore("missing"), ErrUnknownCheckpoint)
+}
+
+func TestHistory_ClampedMove(t *testing.T) {
+	p := &plateau.Plateau{Width: 3, Height: 3, Edge: plateau.EdgeClamp}
+	r := rover.NewRover(rover.WithPosition(models.Coordinates{X: 1, Y: 2}), rover.WithPlateau(p))
+	h := NewHistory(r, p)
+
+	// марсоход у северного края: движение вперёд ничего не меняет и в историю не попадает
+	require.NoError(t, h.Move(1))
+	assert.Equal(t, models.Coordinates{X: 1, Y: 2}, r.GetCurrentPosition())
+	assert.ErrorIs(t, h.Undo(), ErrNothingToUndo)
+	assert.Equal(t, models.Coordinates{X: 1, Y: 2}, r.GetCurrentPosition())
+
+	// из трёх клеток назад марсоход проезжает две, отмена возвращает его на две клетки
+	require.NoError(t, h.Move(-3))
+	assert.Equal(t, models.Coordinates{X: 1, Y: 0}, r.GetCurrentPosition())
+	require.NoError(t, h.Undo())
+	assert.Equal(t, models.Coordinates{X: 1, Y: 2}, r.GetCurrentPosition())
+	require.NoError(t, h.Redo())
+	assert.Equal(t, models.Coordinates{X: 1, Y: 0}, r.GetCurrentPosition())
+}
@@ -0,0 +1,71 @@
+package fleet
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"mars-rover/internal/models"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Spec описание марсохода группы из файла
+type Spec struct {
+	Name      string
+	Start     models.Coordinates
+	Direction models.Direction
+	Commands  string
+}
+
+// LoadFleet загружает описание группы марсоходов из файла
+func LoadFleet(filePath string) ([]Spec, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return ParseFleet(file)
+}
+
+// ParseFleet читает описание группы построчно в формате "имя x y направление команды", например "alpha 1 2 N FFRFF".
+// Команды можно не указывать. Пустые строки и строки, начинающиеся с #, пропускаются
+func ParseFleet(r io.Reader) ([]Spec, error) {
+	var specs []Spec
+	scanner := bufio.NewScanner(r)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		fields := strings.Fields(text)
+		if len(fields) != 4 && len(fields) != 5 {
+			return nil, fmt.Errorf("%w: line %d: expected \"name x y direction commands\", got %q",
+				ErrInvalidFleet, line, text)
+		}
+		x, errX := strconv.Atoi(fields[1])
+		y, errY := strconv.Atoi(fields[2])
+		if errX != nil || errY != nil {
+			return nil, fmt.Errorf("%w: line %d: coordinates must be integers", ErrInvalidFleet, line)
+		}
+		dir, err := models.ParseDirection(fields[3])
+		if err != nil {
+			return nil, fmt.Errorf("%w: line %d: %w", ErrInvalidFleet, line, err)
+		}
+
+		spec := Spec{Name: fields[0], Start: models.Coordinates{X: x, Y: y}, Direction: dir}
+		if len(fields) == 5 {
+			spec.Commands = fields[4]
+		}
+		specs = append(specs, spec)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return specs, nil
+}
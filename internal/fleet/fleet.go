@@ -0,0 +1,197 @@
+package fleet
+
+import (
+	"errors"
+	"fmt"
+	"mars-rover/internal/app"
+	"mars-rover/internal/models"
+	"mars-rover/internal/plateau"
+	"mars-rover/internal/rover"
+)
+
+var ErrInvalidFleet = errors.New("fleet error: invalid fleet configuration")
+
+// Mode порядок выполнения маршрутов группы марсоходов
+type Mode string
+
+const (
+	// Sequential марсоходы выполняют маршруты по очереди, следующий стартует после остановки предыдущего
+	Sequential Mode = "sequential"
+	// Interleaved марсоходы ходят по очереди по одному шагу
+	Interleaved Mode = "interleaved"
+)
+
+// Result итоговое состояние марсохода группы
+type Result struct {
+	Name      string
+	Pos       models.Coordinates
+	Direction models.Direction
+	// Err причина остановки марсохода, nil если маршрут выполнен полностью
+	Err error
+}
+
+// Fleet группа именованных марсоходов на одном плато
+type Fleet struct {
+	Plateau   *plateau.Plateau
+	Optimizer app.Optimizer
+
+	members []*member
+}
+
+type member struct {
+	name     string
+	rover    *rover.Rover
+	commands string
+	route    []models.Move
+	// spans диапазоны исходных команд для движений route, nil если оптимизатор их не сообщает
+	spans []models.Span
+	// next номер текущего движения маршрута, done количество клеток, уже пройденных в этом движении
+	next int
+	done int
+	err  error
+}
+
+func NewFleet(p *plateau.Plateau, optimizer app.Optimizer) *Fleet {
+	return &Fleet{
+		Plateau:   p,
+		Optimizer: optimizer,
+	}
+}
+
+// Add добавляет марсоход в группу. Имена марсоходов и их стартовые клетки не должны повторяться
+func (f *Fleet) Add(name string, start models.Coordinates, dir models.Direction, commands string) error {
+	for _, m := range f.members {
+		if m.name == name {
+			return fmt.Errorf("%w: duplicate rover name %q", ErrInvalidFleet, name)
+		}
+		if m.rover.Pos == start {
+			return fmt.Errorf("%w: rovers %q and %q start at (%d, %d)", ErrInvalidFleet, m.name, name, start.X, start.Y)
+		}
+	}
+	if f.Plateau != nil && (!f.Plateau.Contains(start) || f.Plateau.Blocked(start)) {
+		return fmt.Errorf("%w: rover %q starts outside the plateau or on an obstacle", ErrInvalidFleet, name)
+	}
+
+	f.members = append(f.members, &member{
+		name:     name,
+		rover:    rover.NewRover(rover.WithPosition(start), rover.WithDirection(dir), rover.WithPlateau(f.Plateau)),
+		commands: commands,
+	})
+	return nil
+}
+
+// Run выполняет маршруты всех марсоходов и возвращает итоговое состояние каждого в порядке добавления.
+// Марсоход, который должен был заехать в клетку другого марсохода, останавливается перед ней с ErrRoverCollision
+func (f *Fleet) Run(mode Mode) ([]Result, error) {
+	if mode != Sequential && mode != Interleaved {
+		return nil, fmt.Errorf("%w: unknown mode %q", ErrInvalidFleet, mode)
+	}
+
+	for _, m := range f.members {
+		if o, ok := f.Optimizer.(app.StartAwareOptimizer); ok {
+			o.SetStart(m.rover.Pos, m.rover.Direction)
+		}
+		var (
+			route []models.Move
+			spans []models.Span
+			err   error
+		)
+		if o, ok := f.Optimizer.(app.SpanOptimizer); ok {
+			route, spans, err = o.OptimizeRouteWithSpans(m.commands)
+		} else {
+			route, err = f.Optimizer.OptimizeRoute(m.commands)
+		}
+		if err != nil {
+			m.err = err
+			continue
+		}
+		m.route, m.spans = route, spans
+	}
+
+	if mode == Sequential {
+		for _, m := range f.members {
+			for f.advance(m) {
+			}
+		}
+	} else {
+		for active := true; active; {
+			active = false
+			for _, m := range f.members {
+				active = f.advance(m) || active
+			}
+		}
+	}
+
+	results := make([]Result, 0, len(f.members))
+	for _, m := range f.members {
+		app.LocateError(m.err, m.commands, m.route, m.spans)
+		results = append(results, Result{
+			Name:      m.name,
+			Pos:       m.rover.Pos,
+			Direction: m.rover.Direction,
+			Err:       m.err,
+		})
+	}
+	return results, nil
+}
+
+// advance выполняет следующий шаг марсохода: поворот или перемещение на одну клетку,
+// чтобы столкновения проверялись в каждой клетке схлопнутого движения. Сообщает, остались ли ещё шаги
+func (f *Fleet) advance(m *member) bool {
+	if m.err != nil || m.next >= len(m.route) {
+		return false
+	}
+	index, move := m.next, m.route[m.next]
+
+	if move.Type == models.Rotation {
+		if err := m.rover.Rotate(move.Value); err != nil {
+			m.err = &models.RouteError{Index: -1, Move: index, Pos: m.rover.Pos, Direction: m.rover.Direction, Err: err}
+			return false
+		}
+		m.next++
+		return m.next < len(m.route)
+	}
+
+	unit := 1
+	if move.Value < 0 {
+		unit = -1
+	}
+	done := m.done
+	m.done++
+	if m.done >= move.Value*unit {
+		m.next, m.done = m.next+1, 0
+	}
+	if move.Value == 0 {
+		return m.next < len(m.route)
+	}
+
+	probe := *m.rover
+	if err := probe.Move(unit); err != nil {
+		*m.rover = probe
+		m.err = &models.RouteError{Index: -1, Move: index, Pos: probe.Pos, Direction: probe.Direction, Done: done, Err: err}
+		return false
+	}
+	if other := f.occupant(probe.Pos, m); other != nil {
+		m.err = &models.RouteError{
+			Index:     -1,
+			Move:      index,
+			Pos:       m.rover.Pos,
+			Direction: m.rover.Direction,
+			Done:      done,
+			Err:       fmt.Errorf("%w: rover %q at (%d, %d)", models.ErrRoverCollision, other.name, probe.Pos.X, probe.Pos.Y),
+		}
+		return false
+	}
+
+	*m.rover = probe
+	return m.next < len(m.route)
+}
+
+func (f *Fleet) occupant(cell models.Coordinates, self *member) *member {
+	for _, m := range f.members {
+		if m != self && m.rover.Pos == cell {
+			return m
+		}
+	}
+	return nil
+}
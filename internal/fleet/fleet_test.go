@@ -0,0 +1,194 @@
+package fleet
+
+import (
+	"mars-rover/internal/models"
+	"mars-rover/internal/optimization"
+	"mars-rover/internal/plateau"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFleet_Run(t *testing.T) {
+	type rover struct {
+		name     string
+		start    models.Coordinates
+		dir      models.Direction
+		commands string
+	}
+
+	tests := []struct {
+		name       string
+		plateau    *plateau.Plateau
+		mode       Mode
+		rovers     []rover
+		expected   []Result
+		collisions []int
+	}{
+		{
+			name:    "Classic sequential deployment",
+			plateau: &plateau.Plateau{Width: 6, Height: 6},
+			mode:    Sequential,
+			rovers: []rover{
+				{"1", models.Coordinates{X: 1, Y: 2}, models.North, "LFLFLFLFF"},
+				{"2", models.Coordinates{X: 3, Y: 3}, models.East, "FFRFFRFRRF"},
+			},
+			expected: []Result{
+				{Name: "1", Pos: models.Coordinates{X: 1, Y: 3}, Direction: models.North},
+				{Name: "2", Pos: models.Coordinates{X: 5, Y: 1}, Direction: models.East},
+			},
+		},
+		{
+			name: "Sequential collision with parked rover",
+			mode: Sequential,
+			rovers: []rover{
+				{"alpha", models.Coordinates{X: 1, Y: 2}, models.North, "FF"},
+				{"beta", models.Coordinates{X: 1, Y: 0}, models.North, "FFFFF"},
+			},
+			expected: []Result{
+				{Name: "alpha", Pos: models.Coordinates{X: 1, Y: 4}, Direction: models.North},
+				{Name: "beta", Pos: models.Coordinates{X: 1, Y: 3}, Direction: models.North},
+			},
+			collisions: []int{1},
+		},
+		{
+			name: "Interleaved head-on collision",
+			mode: Interleaved,
+			rovers: []rover{
+				{"alpha", models.Coordinates{X: 0, Y: 0}, models.East, "FFFF"},
+				{"beta", models.Coordinates{X: 4, Y: 0}, models.West, "FFFF"},
+			},
+			expected: []Result{
+				{Name: "alpha", Pos: models.Coordinates{X: 2, Y: 0}, Direction: models.East},
+				{Name: "beta", Pos: models.Coordinates{X: 3, Y: 0}, Direction: models.West},
+			},
+			collisions: []int{0, 1},
+		},
+		{
+			name: "Interleaved rovers pass each other's start cells",
+			mode: Interleaved,
+			rovers: []rover{
+				{"alpha", models.Coordinates{X: 0, Y: 0}, models.East, "F"},
+				{"beta", models.Coordinates{X: 0, Y: 1}, models.South, "RFF"},
+			},
+			expected: []Result{
+				{Name: "alpha", Pos: models.Coordinates{X: 1, Y: 0}, Direction: models.East},
+				{Name: "beta", Pos: models.Coordinates{X: -2, Y: 1}, Direction: models.West},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := NewFleet(tt.plateau, optimization.NewOptimizer())
+			for _, r := range tt.rovers {
+				require.NoError(t, f.Add(r.name, r.start, r.dir, r.commands))
+			}
+
+			results, err := f.Run(tt.mode)
+			require.NoError(t, err)
+			require.Len(t, results, len(tt.expected))
+
+			for i, result := range results {
+				assert.Equal(t, tt.expected[i].Name, result.Name)
+				assert.Equal(t, tt.expected[i].Pos, result.Pos)
+				assert.Equal(t, tt.expected[i].Direction, result.Direction)
+				if contains(tt.collisions, i) {
+					assert.ErrorIs(t, result.Err, models.ErrRoverCollision)
+				} else {
+					assert.NoError(t, result.Err)
+				}
+			}
+		})
+	}
+}
+
+func TestFleet_RunStopsAtObstacle(t *testing.T) {
+	p := &plateau.Plateau{Obstacles: plateau.NewObstacles(models.Coordinates{X: 0, Y: 3})}
+	f := NewFleet(p, optimization.NewOptimizer())
+	require.NoError(t, f.Add("alpha", models.Coordinates{X: 0, Y: 0}, models.North, "RLFFFF"))
+
+	results, err := f.Run(Sequential)
+	require.NoError(t, err)
+	assert.ErrorIs(t, results[0].Err, models.ErrObstacle)
+	assert.Equal(t, models.Coordinates{X: 0, Y: 2}, results[0].Pos)
+
+	// марсоход остановился на третьей F, смещение 4 в исходной строке
+	var routeErr *models.RouteError
+	require.ErrorAs(t, results[0].Err, &routeErr)
+	assert.Equal(t, 4, routeErr.Index)
+	assert.Equal(t, models.Span{Start: 2, End: 6}, routeErr.Span)
+}
+
+func TestFleet_Add(t *testing.T) {
+	f := NewFleet(&plateau.Plateau{Width: 5, Height: 5}, optimization.NewOptimizer())
+	require.NoError(t, f.Add("alpha", models.Coordinates{X: 1, Y: 1}, models.North, "F"))
+
+	assert.ErrorIs(t, f.Add("alpha", models.Coordinates{X: 2, Y: 2}, models.North, ""), ErrInvalidFleet)
+	assert.ErrorIs(t, f.Add("beta", models.Coordinates{X: 1, Y: 1}, models.North, ""), ErrInvalidFleet)
+	assert.ErrorIs(t, f.Add("gamma", models.Coordinates{X: 7, Y: 1}, models.North, ""), ErrInvalidFleet)
+}
+
+func TestFleet_RunInvalidRoute(t *testing.T) {
+	f := NewFleet(nil, optimization.NewOptimizer())
+	require.NoError(t, f.Add("alpha", models.Coordinates{X: 1, Y: 1}, models.North, "FXF"))
+
+	results, err := f.Run(Sequential)
+	require.NoError(t, err)
+	assert.ErrorIs(t, results[0].Err, models.ErrIncorrectSymbol)
+	assert.Equal(t, models.Coordinates{X: 1, Y: 1}, results[0].Pos)
+
+	_, err = f.Run(Mode("parallel"))
+	assert.ErrorIs(t, err, ErrInvalidFleet)
+}
+
+func TestParseFleet(t *testing.T) {
+	tests := []struct {
+		name        string
+		input       string
+		expected    []Spec
+		expectedErr error
+	}{
+		{
+			name:  "Rovers with comments",
+			input: "# разведчики\nalpha 1 2 N FFRFF\n\nbeta -3 0 W\n",
+			expected: []Spec{
+				{Name: "alpha", Start: models.Coordinates{X: 1, Y: 2}, Direction: models.North, Commands: "FFRFF"},
+				{Name: "beta", Start: models.Coordinates{X: -3, Y: 0}, Direction: models.West},
+			},
+		},
+		{
+			name:        "Unknown direction",
+			input:       "alpha 1 2 Q F\n",
+			expectedErr: ErrInvalidFleet,
+		},
+		{
+			name:        "Missing fields",
+			input:       "alpha 1\n",
+			expectedErr: ErrInvalidFleet,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			specs, err := ParseFleet(strings.NewReader(tt.input))
+			if tt.expectedErr != nil {
+				assert.ErrorIs(t, err, tt.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, specs)
+		})
+	}
+}
+
+func contains(values []int, v int) bool {
+	for _, value := range values {
+		if value == v {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,164 @@
+package mission
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"mars-rover/internal/app"
+	"mars-rover/internal/models"
+	"mars-rover/internal/plateau"
+	"mars-rover/internal/rover"
+	"os"
+	"strconv"
+	"strings"
+)
+
+var ErrInvalidMission = errors.New("mission error: invalid input")
+
+// Mission задание в стандартном формате задачи Mars Rover:
+//
+//	5 5
+//	1 2 N
+//	LMLMLMLMM
+//	3 3 E
+//	MMRMMRMRRM
+//
+// Первая строка задаёт верхний правый угол плато, далее идут пары строк с начальным состоянием и командами марсохода
+type Mission struct {
+	Plateau *plateau.Plateau
+	Rovers  []Rover
+}
+
+// Rover начальное состояние и команды марсохода задания
+type Rover struct {
+	Start     models.Coordinates
+	Direction models.Direction
+	// Commands команды марсохода, M уже заменена на F
+	Commands string
+}
+
+// Result итоговое состояние марсохода задания
+type Result struct {
+	Pos       models.Coordinates
+	Direction models.Direction
+	Err       error
+}
+
+// Load загружает задание из файла
+func Load(filePath string) (*Mission, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return Parse(file)
+}
+
+// Parse читает задание в стандартном формате. Пустые строки пропускаются
+func Parse(r io.Reader) (*Mission, error) {
+	scanner := bufio.NewScanner(r)
+	line := 0
+	next := func() (string, bool) {
+		for scanner.Scan() {
+			line++
+			if text := strings.TrimSpace(scanner.Text()); text != "" {
+				return text, true
+			}
+		}
+		return "", false
+	}
+
+	header, ok := next()
+	if !ok {
+		return nil, fmt.Errorf("%w: missing plateau line", ErrInvalidMission)
+	}
+	corner, err := parseNumbers(header, 2)
+	if err != nil || corner[0] < 0 || corner[1] < 0 {
+		return nil, fmt.Errorf("%w: line %d: expected plateau upper-right corner \"x y\", got %q",
+			ErrInvalidMission, line, header)
+	}
+
+	m := &Mission{
+		Plateau: &plateau.Plateau{Width: corner[0] + 1, Height: corner[1] + 1, Edge: plateau.EdgeReject},
+	}
+	for {
+		position, ok := next()
+		if !ok {
+			break
+		}
+		fields := strings.Fields(position)
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("%w: line %d: expected rover position \"x y direction\", got %q",
+				ErrInvalidMission, line, position)
+		}
+		start, err := parseNumbers(strings.Join(fields[:2], " "), 2)
+		if err != nil {
+			return nil, fmt.Errorf("%w: line %d: coordinates must be integers", ErrInvalidMission, line)
+		}
+		dir, err := models.ParseDirection(fields[2])
+		if err != nil {
+			return nil, fmt.Errorf("%w: line %d: %w", ErrInvalidMission, line, err)
+		}
+
+		commands, ok := next()
+		if !ok {
+			return nil, fmt.Errorf("%w: line %d: missing commands for rover %d", ErrInvalidMission, line, len(m.Rovers)+1)
+		}
+		m.Rovers = append(m.Rovers, Rover{
+			Start:     models.Coordinates{X: start[0], Y: start[1]},
+			Direction: dir,
+			Commands:  strings.ReplaceAll(commands, "M", "F"),
+		})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+// Run выполняет маршруты марсоходов по очереди, каждый через собственный app.App на общем плато
+func (m *Mission) Run(optimizer app.Optimizer) []Result {
+	results := make([]Result, 0, len(m.Rovers))
+	for _, spec := range m.Rovers {
+		if !m.Plateau.Contains(spec.Start) || m.Plateau.Blocked(spec.Start) {
+			results = append(results, Result{
+				Pos:       spec.Start,
+				Direction: spec.Direction,
+				Err: fmt.Errorf("%w: rover starts at (%d, %d) outside the plateau or on an obstacle",
+					ErrInvalidMission, spec.Start.X, spec.Start.Y),
+			})
+			continue
+		}
+
+		r := rover.NewRover(rover.WithPosition(spec.Start), rover.WithDirection(spec.Direction))
+		a := app.NewApp(r, optimizer, app.WithPlateau(m.Plateau))
+
+		_, _, err := a.HandleCommands(spec.Commands)
+		results = append(results, Result{Pos: r.GetCurrentPosition(), Direction: r.GetCurrentDirection(), Err: err})
+	}
+	return results
+}
+
+// FormatState записывает состояние марсохода в стандартном формате: "1 3 N"
+func FormatState(pos models.Coordinates, dir models.Direction) string {
+	return fmt.Sprintf("%d %d %s", pos.X, pos.Y, dir)
+}
+
+func parseNumbers(text string, count int) ([]int, error) {
+	fields := strings.Fields(text)
+	if len(fields) != count {
+		return nil, fmt.Errorf("expected %d numbers, got %d", count, len(fields))
+	}
+	numbers := make([]int, 0, count)
+	for _, field := range fields {
+		n, err := strconv.Atoi(field)
+		if err != nil {
+			return nil, err
+		}
+		numbers = append(numbers, n)
+	}
+	return numbers, nil
+}
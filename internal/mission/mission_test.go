@@ -0,0 +1,103 @@
+package mission
+
+import (
+	"mars-rover/internal/models"
+	"mars-rover/internal/optimization"
+	"mars-rover/internal/plateau"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const classicInput = `5 5
+1 2 N
+LMLMLMLMM
+3 3 E
+MMRMMRMRRM
+`
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name        string
+		input       string
+		expected    *Mission
+		expectedErr error
+	}{
+		{
+			name:  "Classic input",
+			input: classicInput,
+			expected: &Mission{
+				Plateau: &plateau.Plateau{Width: 6, Height: 6, Edge: plateau.EdgeReject},
+				Rovers: []Rover{
+					{Start: models.Coordinates{X: 1, Y: 2}, Direction: models.North, Commands: "LFLFLFLFF"},
+					{Start: models.Coordinates{X: 3, Y: 3}, Direction: models.East, Commands: "FFRFFRFRRF"},
+				},
+			},
+		},
+		{
+			name:  "Blank lines are skipped",
+			input: "\n2 3\n\n0 0 E\n\nMM\n",
+			expected: &Mission{
+				Plateau: &plateau.Plateau{Width: 3, Height: 4, Edge: plateau.EdgeReject},
+				Rovers: []Rover{
+					{Start: models.Coordinates{X: 0, Y: 0}, Direction: models.East, Commands: "FF"},
+				},
+			},
+		},
+		{
+			name:        "Empty input",
+			input:       "",
+			expectedErr: ErrInvalidMission,
+		},
+		{
+			name:        "Invalid plateau",
+			input:       "5\n1 2 N\nM\n",
+			expectedErr: ErrInvalidMission,
+		},
+		{
+			name:        "Invalid direction",
+			input:       "5 5\n1 2 Q\nM\n",
+			expectedErr: ErrInvalidMission,
+		},
+		{
+			name:        "Missing commands",
+			input:       "5 5\n1 2 N\n",
+			expectedErr: ErrInvalidMission,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := Parse(strings.NewReader(tt.input))
+			if tt.expectedErr != nil {
+				assert.ErrorIs(t, err, tt.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, m)
+		})
+	}
+}
+
+func TestMission_Run(t *testing.T) {
+	m, err := Parse(strings.NewReader(classicInput + "6 0 N\nM\n4 4 N\nMM\n0 0 N\nMXM\n"))
+	require.NoError(t, err)
+
+	results := m.Run(optimization.NewOptimizer())
+	require.Len(t, results, 5)
+
+	assert.Equal(t, "1 3 N", FormatState(results[0].Pos, results[0].Direction))
+	assert.NoError(t, results[0].Err)
+	assert.Equal(t, "5 1 E", FormatState(results[1].Pos, results[1].Direction))
+	assert.NoError(t, results[1].Err)
+
+	assert.ErrorIs(t, results[2].Err, ErrInvalidMission)
+
+	assert.Equal(t, "4 5 N", FormatState(results[3].Pos, results[3].Direction))
+	assert.ErrorIs(t, results[3].Err, models.ErrOutOfBounds)
+
+	assert.Equal(t, "0 0 N", FormatState(results[4].Pos, results[4].Direction))
+	assert.ErrorIs(t, results[4].Err, models.ErrIncorrectSymbol)
+}
@@ -6,6 +6,7 @@ package mocks
 
 import (
 	models "mars-rover/internal/models"
+	plateau "mars-rover/internal/plateau"
 	reflect "reflect"
 
 	gomock "github.com/golang/mock/gomock"
@@ -63,9 +64,11 @@ func (mr *MockRoverMockRecorder) GetCurrentPosition() *gomock.Call {
 }
 
 // Move mocks base method.
-func (m *MockRover) Move(arg0 int) {
+func (m *MockRover) Move(arg0 int) error {
 	m.ctrl.T.Helper()
-	m.ctrl.Call(m, "Move", arg0)
+	ret := m.ctrl.Call(m, "Move", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
 }
 
 // Move indicates an expected call of Move.
@@ -75,9 +78,11 @@ func (mr *MockRoverMockRecorder) Move(arg0 interface{}) *gomock.Call {
 }
 
 // PerformRoute mocks base method.
-func (m *MockRover) PerformRoute(arg0 []models.Move) {
+func (m *MockRover) PerformRoute(arg0 []models.Move) error {
 	m.ctrl.T.Helper()
-	m.ctrl.Call(m, "PerformRoute", arg0)
+	ret := m.ctrl.Call(m, "PerformRoute", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
 }
 
 // PerformRoute indicates an expected call of PerformRoute.
@@ -86,10 +91,24 @@ func (mr *MockRoverMockRecorder) PerformRoute(arg0 interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PerformRoute", reflect.TypeOf((*MockRover)(nil).PerformRoute), arg0)
 }
 
+// SetPlateau mocks base method.
+func (m *MockRover) SetPlateau(arg0 *plateau.Plateau) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetPlateau", arg0)
+}
+
+// SetPlateau indicates an expected call of SetPlateau.
+func (mr *MockRoverMockRecorder) SetPlateau(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetPlateau", reflect.TypeOf((*MockRover)(nil).SetPlateau), arg0)
+}
+
 // Rotate mocks base method.
-func (m *MockRover) Rotate(arg0 int) {
+func (m *MockRover) Rotate(arg0 int) error {
 	m.ctrl.T.Helper()
-	m.ctrl.Call(m, "Rotate", arg0)
+	ret := m.ctrl.Call(m, "Rotate", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
 }
 
 // Rotate indicates an expected call of Rotate.
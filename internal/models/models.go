@@ -1,9 +1,25 @@
 package models
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// contextRadius количество символов маршрута по обе стороны от недопустимого символа, которые попадают в Context
+const contextRadius = 20
 
 var (
-	ErrIncorrectSymbol = errors.New("validation error: unexpected input")
+	ErrIncorrectSymbol  = errors.New("validation error: unexpected input")
+	ErrRouteSyntax      = errors.New("syntax error: malformed route")
+	ErrOutOfBounds      = errors.New("boundary error: move leaves the plateau")
+	ErrObstacle         = errors.New("collision error: cell is blocked by an obstacle")
+	ErrRoverCollision   = errors.New("collision error: cell is occupied by another rover")
+	ErrInvalidDirection = errors.New("validation error: invalid direction")
+	ErrBatteryDepleted  = errors.New("energy error: battery depleted")
+	// ErrRouteTooLong маршрут корректен, но слишком длинный для выбранной стратегии оптимизации
+	ErrRouteTooLong = errors.New("limit error: route is too long")
 )
 
 type Direction string
@@ -15,6 +31,30 @@ const (
 	West  Direction = "W"
 )
 
+// DirectionError ошибка разбора направления марсохода
+type DirectionError struct {
+	// Value строка, которую не удалось разобрать
+	Value string
+}
+
+func (e *DirectionError) Error() string {
+	return fmt.Sprintf("%v: %q, expected one of N, S, E, W", ErrInvalidDirection, e.Value)
+}
+
+func (e *DirectionError) Unwrap() error {
+	return ErrInvalidDirection
+}
+
+// ParseDirection разбирает направление марсохода из строки N, S, E или W
+func ParseDirection(value string) (Direction, error) {
+	switch dir := Direction(value); dir {
+	case North, South, East, West:
+		return dir, nil
+	default:
+		return "", &DirectionError{Value: value}
+	}
+}
+
 type Coordinates struct {
 	X int
 	Y int
@@ -34,3 +74,179 @@ type Move struct {
 	// Value при Type = Movement Value означает количество шагов, при Type = Rotation Value означает количество поворотов на 90 градусов против часовой стрелки
 	Value int
 }
+
+// Span диапазон исходного маршрута [Start, End) в байтах, из которого получено движение
+type Span struct {
+	Start int
+	End   int
+}
+
+// Union возвращает наименьший диапазон, содержащий оба диапазона. Нулевой диапазон считается пустым
+func (s Span) Union(other Span) Span {
+	if s == (Span{}) {
+		return other
+	}
+	if other == (Span{}) {
+		return s
+	}
+	return Span{Start: min(s.Start, other.Start), End: max(s.End, other.End)}
+}
+
+// RouteError ошибка, прервавшая выполнение маршрута
+type RouteError struct {
+	// Index смещение в байтах исходной команды маршрута, на которой остановился марсоход, в интерактивном режиме -
+	// номер команды оператора. -1, если исходная команда неизвестна: Rover.PerformRoute получает только движения
+	// и сообщает Move, а Locate находит по нему исходную команду
+	Index int
+	// Move номер движения в маршруте, переданном Rover.PerformRoute
+	Move int
+	// Location место исходной команды в файле маршрута, пустое для маршрута из строки
+	Location string
+	// Pos позиция, в которой остановился марсоход
+	Pos Coordinates
+	// Direction направление марсохода в момент остановки
+	Direction Direction
+	// Span команды исходного маршрута, из которых получено движение, нулевой, если оптимизатор не сообщает диапазоны
+	Span Span
+	// Done сколько клеток или поворотов движения марсоход выполнил до ошибки
+	Done int
+	// Err причина остановки
+	Err error
+}
+
+// Commands перебирает команды исходного маршрута: команду, число повторов и её диапазон в маршруте.
+// Перебор прекращается, когда yield возвращает false
+type Commands func(yield func(command rune, count int, span Span) bool)
+
+// Locate переводит ошибку движения move, полученного из команд span, в исходный маршрут. Команды диапазона
+// из commands проигрываются с начала движения, и Index становится смещением команды, на которой марсоход
+// дошёл бы до клетки или поворота, где он остановился. Если commands nil или команды диапазона не складываются
+// в движение move, например после геометрической оптимизации, Index указывает на начало диапазона
+func (e *RouteError) Locate(move Move, span Span, commands Commands) {
+	e.Span = span
+	e.Index = span.Start
+	if commands == nil {
+		return
+	}
+
+	unit := 1
+	if move.Value < 0 {
+		unit = -1
+	}
+	// target сколько клеток или четвертей оборота марсоход сделал бы вместе с несостоявшимся шагом
+	target := (e.Done + 1) * unit
+	reached, started, index := 0, false, -1
+	commands(func(command rune, count int, s Span) bool {
+		if s.Start < span.Start || s.End > span.End {
+			// команды до диапазона пропускаются, на первой команде после него проигрыш заканчивается
+			return !started
+		}
+		started = true
+		step := commandStep(command, move.Type)
+		if step == 0 {
+			index = -1
+			return false
+		}
+		next := reached + step*count
+		if step*unit > 0 && reached*unit < target*unit && next*unit >= target*unit {
+			index = s.Start
+			return false
+		}
+		reached = next
+		return true
+	})
+	if index >= 0 {
+		e.Index = index
+	}
+}
+
+// commandStep направление, в котором команда меняет движение типа t: 1 для F и L, -1 для B и R,
+// 0 для команды другого типа
+func commandStep(command rune, t MoveType) int {
+	switch {
+	case t == Movement && command == 'F', t == Rotation && command == 'L':
+		return 1
+	case t == Movement && command == 'B', t == Rotation && command == 'R':
+		return -1
+	}
+	return 0
+}
+
+func (e *RouteError) Error() string {
+	at := fmt.Sprintf("command %d", e.Index)
+	if e.Index < 0 {
+		at = fmt.Sprintf("move %d", e.Move)
+	}
+	return fmt.Sprintf("route stopped at %s, position (%d, %d) %s: %v",
+		at, e.Pos.X, e.Pos.Y, e.Direction, e.Err)
+}
+
+func (e *RouteError) Unwrap() error {
+	return e.Err
+}
+
+// SymbolError недопустимый символ маршрута
+type SymbolError struct {
+	// Offset смещение символа в байтах от начала маршрута
+	Offset int
+	Symbol rune
+	// Location место символа в файле маршрута в виде файл:строка:столбец, пустое для маршрута из консоли
+	Location string
+	// Context фрагмент маршрута вокруг символа
+	Context string
+	// Caret позиция символа во фрагменте Context в символах, считая с нуля
+	Caret int
+}
+
+// NewSymbolError создаёт ошибку для символа по смещению offset в строке text с фрагментом строки вокруг него
+func NewSymbolError(text string, offset int) *SymbolError {
+	symbol, size := utf8.DecodeRuneInString(text[offset:])
+
+	start, caret := offset, 0
+	for ; start > 0 && caret < contextRadius; caret++ {
+		_, size := utf8.DecodeLastRuneInString(text[:start])
+		start -= size
+	}
+	end := offset + size
+	for i := 0; end < len(text) && i < contextRadius; i++ {
+		_, size := utf8.DecodeRuneInString(text[end:])
+		end += size
+	}
+
+	return &SymbolError{
+		Offset:  offset,
+		Symbol:  symbol,
+		Context: strings.ReplaceAll(text[start:end], "\t", " "),
+		Caret:   caret,
+	}
+}
+
+func (e *SymbolError) Error() string {
+	if e.Location != "" {
+		return fmt.Sprintf("%q at %s", e.Symbol, e.Location)
+	}
+	return fmt.Sprintf("%q at offset %d", e.Symbol, e.Offset)
+}
+
+func (e *SymbolError) Unwrap() error {
+	return ErrIncorrectSymbol
+}
+
+// SymbolErrors все недопустимые символы маршрута, найденные за один проход
+type SymbolErrors []*SymbolError
+
+func (e SymbolErrors) Error() string {
+	symbols := make([]string, 0, len(e))
+	for _, symbolErr := range e {
+		symbols = append(symbols, symbolErr.Error())
+	}
+	return fmt.Sprintf("%v: %s", ErrIncorrectSymbol, strings.Join(symbols, ", "))
+}
+
+func (e SymbolErrors) Unwrap() []error {
+	errs := make([]error, 0, len(e))
+	for _, symbolErr := range e {
+		errs = append(errs, symbolErr)
+	}
+	return errs
+}
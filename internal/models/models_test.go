@@ -0,0 +1,168 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseDirection(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    string
+		expected Direction
+		valid    bool
+	}{
+		{"North", "N", North, true},
+		{"South", "S", South, true},
+		{"East", "E", East, true},
+		{"West", "W", West, true},
+		{"Lowercase", "n", "", false},
+		{"Unknown", "Q", "", false},
+		{"Empty", "", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir, err := ParseDirection(tt.value)
+			if tt.valid {
+				require.NoError(t, err)
+				assert.Equal(t, tt.expected, dir)
+				return
+			}
+
+			var dirErr *DirectionError
+			require.ErrorAs(t, err, &dirErr)
+			assert.Equal(t, tt.value, dirErr.Value)
+			assert.ErrorIs(t, err, ErrInvalidDirection)
+		})
+	}
+}
+
+func TestNewSymbolError(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		offset   int
+		expected *SymbolError
+	}{
+		{
+			name:     "Short route",
+			text:     "FFXB",
+			offset:   2,
+			expected: &SymbolError{Offset: 2, Symbol: 'X', Context: "FFXB", Caret: 2},
+		},
+		{
+			name:     "Multibyte symbols before the caret",
+			text:     "ЖЖ\tЖF",
+			offset:   5,
+			expected: &SymbolError{Offset: 5, Symbol: 'Ж', Context: "ЖЖ ЖF", Caret: 3},
+		},
+		{
+			name:   "Long route is cut",
+			text:   "0123456789012345678901234567890123456789X0123456789012345678901234567890123456789",
+			offset: 40,
+			expected: &SymbolError{
+				Offset: 40, Symbol: 'X', Context: "01234567890123456789X01234567890123456789", Caret: 20,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, NewSymbolError(tt.text, tt.offset))
+		})
+	}
+}
+
+func TestSymbolErrors(t *testing.T) {
+	var err error = SymbolErrors{
+		{Offset: 2, Symbol: 'X'},
+		{Offset: 5, Symbol: 'Y', Location: "route.txt:2:1"},
+	}
+
+	assert.EqualError(t, err, "validation error: unexpected input: 'X' at offset 2, 'Y' at route.txt:2:1")
+	assert.ErrorIs(t, err, ErrIncorrectSymbol)
+
+	var symbolErr *SymbolError
+	require.ErrorAs(t, err, &symbolErr)
+	assert.Equal(t, 2, symbolErr.Offset)
+}
+
+func TestSpan_Union(t *testing.T) {
+	tests := []struct {
+		name     string
+		a, b     Span
+		expected Span
+	}{
+		{name: "Empty and span", a: Span{}, b: Span{Start: 2, End: 4}, expected: Span{Start: 2, End: 4}},
+		{name: "Span and empty", a: Span{Start: 2, End: 4}, b: Span{}, expected: Span{Start: 2, End: 4}},
+		{name: "Adjacent spans", a: Span{Start: 0, End: 3}, b: Span{Start: 3, End: 5}, expected: Span{Start: 0, End: 5}},
+		{name: "Nested spans", a: Span{Start: 3, End: 4}, b: Span{Start: 1, End: 9}, expected: Span{Start: 1, End: 9}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, tt.a.Union(tt.b))
+		})
+	}
+}
+
+// commands перебирает команды маршрута route вида 10F2B с диапазонами, как их сообщает routelang
+func commands(route string) Commands {
+	return func(yield func(rune, int, Span) bool) {
+		count, start := 0, 0
+		for i, c := range route {
+			if c >= '0' && c <= '9' {
+				count = count*10 + int(c-'0')
+				continue
+			}
+			if !yield(c, max(count, 1), Span{Start: start, End: i + 1}) {
+				return
+			}
+			count, start = 0, i+1
+		}
+	}
+}
+
+func TestRouteError_Locate(t *testing.T) {
+	tests := []struct {
+		name     string
+		move     Move
+		span     Span
+		commands Commands
+		done     int
+		expected int
+	}{
+		{name: "Collapsed movement", move: Move{Type: Movement, Value: 5}, span: Span{Start: 2, End: 7},
+			commands: commands("RLFFFFF"), done: 2, expected: 4},
+		{name: "Collapsed backward movement", move: Move{Type: Movement, Value: -3}, span: Span{Start: 0, End: 3},
+			commands: commands("BBB"), done: 1, expected: 1},
+		{name: "Collapsed rotation", move: Move{Type: Rotation, Value: -3}, span: Span{Start: 4, End: 7},
+			commands: commands("FFFFRRR"), done: 2, expected: 6},
+		{name: "Repeat count", move: Move{Type: Movement, Value: 10}, span: Span{Start: 1, End: 4},
+			commands: commands("L10F"), done: 6, expected: 1},
+		// B4F: марсоход отъезжает назад командой B и упирается в препятствие на первом шаге 4F
+		{name: "Backward command before repeat count", move: Move{Type: Movement, Value: 3}, span: Span{Start: 0, End: 3},
+			commands: commands("B4F"), done: 0, expected: 1},
+		{name: "Commands cancel out", move: Move{Type: Movement, Value: 1}, span: Span{Start: 0, End: 5},
+			commands: commands("FFFBB"), done: 0, expected: 0},
+		{name: "Backward after forward", move: Move{Type: Movement, Value: -2}, span: Span{Start: 0, End: 4},
+			commands: commands("FBBB"), done: 1, expected: 3},
+		{name: "Commands after span", move: Move{Type: Movement, Value: 2}, span: Span{Start: 0, End: 2},
+			commands: commands("FFFF"), done: 1, expected: 1},
+		{name: "Mixed commands", move: Move{Type: Movement, Value: 3}, span: Span{Start: 1, End: 5},
+			commands: commands("RFFLF"), done: 2, expected: 1},
+		{name: "No source commands", move: Move{Type: Movement, Value: 5}, span: Span{Start: 2, End: 7}, done: 2, expected: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := &RouteError{Index: -1, Move: 1, Done: tt.done}
+			e.Locate(tt.move, tt.span, tt.commands)
+			assert.Equal(t, tt.expected, e.Index)
+			assert.Equal(t, tt.span, e.Span)
+		})
+	}
+}
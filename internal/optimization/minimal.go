@@ -0,0 +1,134 @@
+package optimization
+
+import (
+	"mars-rover/internal/models"
+	"mars-rover/internal/routelang"
+)
+
+// MinimalOptimizer сводит маршрут к итоговому смещению и направлению и строит кратчайшую последовательность
+// движений с тем же конечным состоянием: FRFRFRFR => пустой маршрут. Промежуточные клетки не сохраняются,
+// поэтому оптимизатор не учитывает препятствия и края плато
+type MinimalOptimizer struct{}
+
+func NewMinimalOptimizer() *MinimalOptimizer {
+	return &MinimalOptimizer{}
+}
+
+func (o *MinimalOptimizer) OptimizeRoute(commands string) ([]models.Move, error) {
+	moves, _, err := o.OptimizeRouteWithSpans(commands)
+	return moves, err
+}
+
+// OptimizeRouteWithSpans оптимизирует маршрут как OptimizeRoute. Каждое движение получено из всего маршрута,
+// поэтому диапазон каждого движения - вся строка команд
+func (o *MinimalOptimizer) OptimizeRouteWithSpans(commands string) ([]models.Move, []models.Span, error) {
+	program, err := routelang.Parse(commands)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	net := effect(program.Nodes)
+	moves := minimalMoves(net.forward, net.left, net.heading)
+	spans := make([]models.Span, len(moves))
+	for i := range spans {
+		spans[i] = models.Span{Start: 0, End: len(commands)}
+	}
+	return moves, spans, nil
+}
+
+// minimalMoves строит кратчайшую последовательность: движение вдоль начального направления, поворот,
+// движение поперёк и поворот в итоговое направление. Из двух поворотов выбирается тот, после которого
+// итоговый поворот короче
+func minimalMoves(forward, left, heading int) []models.Move {
+	var best []models.Move
+	for i, turn := range []int{1, -1} {
+		moves := []models.Move{}
+		if forward != 0 {
+			moves = append(moves, models.Move{Type: models.Movement, Value: forward})
+		}
+		current := 0
+		if left != 0 {
+			moves = append(moves,
+				models.Move{Type: models.Rotation, Value: turn},
+				models.Move{Type: models.Movement, Value: left * turn})
+			current = turn
+		}
+		if rest := quarter(heading - current); rest != 0 {
+			moves = append(moves, models.Move{Type: models.Rotation, Value: rest})
+		}
+		if i == 0 || len(moves) < len(best) || len(moves) == len(best) && turns(moves) < turns(best) {
+			best = moves
+		}
+	}
+	return best
+}
+
+// pose итог части маршрута: смещение вдоль начального направления и влево от него и количество поворотов налево
+type pose struct {
+	forward int
+	left    int
+	heading int
+}
+
+// then возвращает итог маршрута, в котором после p выполняется next
+func (p pose) then(next pose) pose {
+	forward, left := next.forward, next.left
+	for i := 0; i < p.heading; i++ {
+		forward, left = -left, forward
+	}
+	return pose{forward: p.forward + forward, left: p.left + left, heading: (p.heading + next.heading) % 4}
+}
+
+// repeat возвращает итог маршрута p, повторённого count раз. Повторы складываются удвоением,
+// поэтому 1000000000000(FRFRFRFR) не перебирается по итерациям
+func (p pose) repeat(count int) pose {
+	result := pose{}
+	for ; count > 0; count >>= 1 {
+		if count&1 == 1 {
+			result = result.then(p)
+		}
+		p = p.then(p)
+	}
+	return result
+}
+
+// effect считает итог узлов маршрута
+func effect(nodes []routelang.Node) pose {
+	total := pose{}
+	for _, node := range nodes {
+		var p pose
+		switch n := node.(type) {
+		case *routelang.Command:
+			switch n.Symbol {
+			case 'F', 'B':
+				p = pose{forward: move(n.Symbol, 0) * n.Count}
+			case 'L', 'R':
+				p = pose{heading: (rotate(n.Symbol, 0)*(n.Count%4) + 4) % 4}
+			}
+		case *routelang.Group:
+			p = effect(n.Body).repeat(n.Count)
+		}
+		total = total.then(p)
+	}
+	return total
+}
+
+// quarter приводит поворот к кратчайшему: 3 поворота налево => 1 направо
+func quarter(turns int) int {
+	turns = (turns%4 + 4) % 4
+	if turns == 3 {
+		return -1
+	}
+	return turns
+}
+
+// turns количество поворотов на 90 градусов в маршруте
+func turns(moves []models.Move) int {
+	total := 0
+	for _, m := range moves {
+		if m.Type == models.Rotation {
+			total += abs(m.Value)
+		}
+	}
+	return total
+}
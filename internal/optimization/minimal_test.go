@@ -0,0 +1,108 @@
+package optimization
+
+import (
+	"mars-rover/internal/models"
+	"mars-rover/internal/rover"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMinimalOptimizer_OptimizeRoute(t *testing.T) {
+	tests := []struct {
+		name          string
+		commands      string
+		expectedMoves []models.Move
+	}{
+		{
+			name:          "Empty route",
+			commands:      "",
+			expectedMoves: []models.Move{},
+		},
+		{
+			name:          "Full square returns home",
+			commands:      "FRFRFRFR",
+			expectedMoves: []models.Move{},
+		},
+		{
+			name:          "Square without last turn",
+			commands:      "FRFRFRF",
+			expectedMoves: []models.Move{{Type: models.Rotation, Value: 1}},
+		},
+		{
+			name:     "Staircase",
+			commands: "FLFRFLFR",
+			expectedMoves: []models.Move{
+				{Type: models.Movement, Value: 2},
+				{Type: models.Rotation, Value: 1},
+				{Type: models.Movement, Value: 2},
+				{Type: models.Rotation, Value: -1},
+			},
+		},
+		{
+			name:     "Sideways move ends facing sideways",
+			commands: "RFFF",
+			expectedMoves: []models.Move{
+				{Type: models.Rotation, Value: -1},
+				{Type: models.Movement, Value: 3},
+			},
+		},
+		{
+			name:     "Turn around",
+			commands: "LLFF",
+			expectedMoves: []models.Move{
+				{Type: models.Movement, Value: -2},
+				{Type: models.Rotation, Value: 2},
+			},
+		},
+		{
+			name:          "Huge loop is not expanded",
+			commands:      "1000000000000(FRFRFRFR)3L",
+			expectedMoves: []models.Move{{Type: models.Rotation, Value: -1}},
+		},
+	}
+
+	optimizer := NewMinimalOptimizer()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			moves, err := optimizer.OptimizeRoute(tt.commands)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedMoves, moves)
+		})
+	}
+}
+
+func TestMinimalOptimizer_Spans(t *testing.T) {
+	_, spans, err := NewMinimalOptimizer().OptimizeRouteWithSpans("FFLF")
+	require.NoError(t, err)
+	assert.Equal(t, []models.Span{{Start: 0, End: 4}, {Start: 0, End: 4}, {Start: 0, End: 4}}, spans)
+}
+
+// TestMinimalOptimizer_Equivalence проверяет на случайных маршрутах, что минимальный маршрут
+// приводит марсоход в то же состояние, что и исходный маршрут без оптимизации
+func TestMinimalOptimizer_Equivalence(t *testing.T) {
+	random := rand.New(rand.NewSource(17))
+	directions := []models.Direction{models.North, models.West, models.South, models.East}
+
+	for i := 0; i < 200; i++ {
+		route := make([]byte, random.Intn(30))
+		for j := range route {
+			route[j] = "FBLR"[random.Intn(4)]
+		}
+		start := rover.Rover{Pos: models.Coordinates{X: 1, Y: 1}, Direction: directions[random.Intn(4)]}
+
+		raw, err := NewPassthroughOptimizer().OptimizeRoute(string(route))
+		require.NoError(t, err)
+		minimal, err := NewMinimalOptimizer().OptimizeRoute(string(route))
+		require.NoError(t, err)
+		assert.LessOrEqual(t, len(minimal), 4, "route %s", route)
+
+		original, optimized := start, start
+		require.NoError(t, original.PerformRoute(raw))
+		require.NoError(t, optimized.PerformRoute(minimal))
+		assert.Equal(t, original.Pos, optimized.Pos, "route %s from %s", route, start.Direction)
+		assert.Equal(t, original.Direction, optimized.Direction, "route %s from %s", route, start.Direction)
+	}
+}
@@ -3,6 +3,7 @@ package optimization
 import (
 	"fmt"
 	"mars-rover/internal/models"
+	"mars-rover/internal/routelang"
 )
 
 type Optimizer struct{}
@@ -13,51 +14,101 @@ func NewOptimizer() *Optimizer {
 
 // OptimizeRoute метод для оптимизации последовательности команд в последовательность движений
 // упрощает множественные последовательности из вперёд-назад и поворотов,
-// чтобы марсоход не бегал много раз назад-вперёд или не крутился на месте
+// чтобы марсоход не бегал много раз назад-вперёд или не крутился на месте.
+// Маршрут может содержать повторы и группы: 10F, 3(FFR), 2(F3(LF))
 func (o *Optimizer) OptimizeRoute(commands string) ([]models.Move, error) {
+	moves, _, err := o.OptimizeRouteWithSpans(commands)
+	return moves, err
+}
+
+// OptimizeRouteWithSpans оптимизирует маршрут как OptimizeRoute и для каждого движения возвращает диапазон
+// исходной строки, из которого оно получено: FFFBB => Move{Movement, 1} с диапазоном [0, 5)
+func (o *Optimizer) OptimizeRouteWithSpans(commands string) ([]models.Move, []models.Span, error) {
 	if len(commands) == 0 {
-		return []models.Move{}, nil
+		return []models.Move{}, []models.Span{}, nil
+	}
+
+	program, err := routelang.Parse(commands)
+	if err != nil {
+		return nil, nil, err
+	}
+	// группы из движений и поворотов вперемешку раскрываются по итерациям: 1000000000000(FR) раскрылась бы
+	// в 2*10^12 команд при маршруте в несколько байт. Длинный маршрут без таких групп не ограничивается
+	if steps := program.Steps(); steps > max(maxUnrolledLength, len(commands)) {
+		return nil, nil, fmt.Errorf("%w: %d commands after expanding mixed groups", models.ErrRouteTooLong, steps)
 	}
 
-	var state models.MoveType
 	moves := make([]models.Move, 0, len(commands))
+	spans := make([]models.Span, 0, len(commands))
+	b := &builder{emit: func(m models.Move, span models.Span) {
+		moves = append(moves, m)
+		spans = append(spans, span)
+	}}
+	program.Walk(func(command rune, count int, span models.Span) bool {
+		b.add(command, count, span)
+		return true
+	})
+	b.finish()
+	return moves, spans, nil
+}
 
-	turns := 0
-	steps := 0
+// builder схлопывает поток команд в движения: подряд идущие движения складываются в одно,
+// подряд идущие повороты - в один поворот
+type builder struct {
+	state models.MoveType
+	turns int
+	steps int
+	// span диапазон исходной строки, из которого собирается текущая серия команд
+	span models.Span
+	// emit получает каждое собранное движение с диапазоном исходных команд
+	emit func(m models.Move, span models.Span)
+}
 
-	for _, command := range commands {
-		switch command {
-		case 'F', 'B':
-			steps = move(command, steps)
-			if state == models.Rotation && turns%4 != 0 {
-				moves = append(moves, models.Move{Type: models.Rotation, Value: turns % 4})
-				turns = 0
-			}
-			state = models.Movement
-		case 'R', 'L':
-			turns = rotate(command, turns)
-			if state == models.Movement && steps != 0 {
-				moves = append(moves, models.Move{Type: models.Movement, Value: steps})
-				steps = 0
-			}
-			state = models.Rotation
-		default:
-			return nil, fmt.Errorf("%w: %c", models.ErrIncorrectSymbol, command)
+// add добавляет команду, повторённую count раз, span диапазон команды в исходной строке
+func (b *builder) add(command rune, count int, span models.Span) {
+	switch command {
+	case 'F', 'B':
+		b.steps += move(command, 0) * count
+		if b.state == models.Rotation && b.turns%4 != 0 {
+			b.flush(models.Move{Type: models.Rotation, Value: b.turns % 4})
+			b.turns = 0
+		}
+		b.extend(models.Movement, span)
+	case 'R', 'L':
+		b.turns += rotate(command, 0) * count
+		if b.state == models.Movement && b.steps != 0 {
+			b.flush(models.Move{Type: models.Movement, Value: b.steps})
+			b.steps = 0
 		}
+		b.extend(models.Rotation, span)
 	}
+}
 
-	if state == models.Rotation && turns%4 == 0 || state == models.Movement && steps == 0 {
-		return moves, nil
+// extend продолжает серию команд типа state или начинает новую серию
+func (b *builder) extend(state models.MoveType, span models.Span) {
+	if b.state != state {
+		b.span = models.Span{}
 	}
+	b.span = b.span.Union(span)
+	b.state = state
+}
+
+func (b *builder) flush(m models.Move) {
+	b.emit(m, b.span)
+}
 
-	switch state {
+// finish завершает последнюю серию команд
+func (b *builder) finish() {
+	if b.state == models.Rotation && b.turns%4 == 0 || b.state == models.Movement && b.steps == 0 {
+		return
+	}
+
+	switch b.state {
 	case models.Rotation:
-		moves = append(moves, models.Move{Type: models.Rotation, Value: turns % 4})
+		b.flush(models.Move{Type: models.Rotation, Value: b.turns % 4})
 	case models.Movement:
-		moves = append(moves, models.Move{Type: models.Movement, Value: steps})
+		b.flush(models.Move{Type: models.Movement, Value: b.steps})
 	}
-
-	return moves, nil
 }
 
 func move(command rune, count int) int {
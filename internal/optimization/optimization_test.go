@@ -97,6 +97,64 @@ func TestOptimizeRoute(t *testing.T) {
 			},
 			expectedErr: models.ErrIncorrectSymbol,
 		},
+		{
+			name:     "Repeat count",
+			commands: "10F",
+			expectedMoves: []models.Move{
+				{Type: models.Movement, Value: 10},
+			},
+		},
+		{
+			name:     "Repeated group",
+			commands: "3(FFR)",
+			expectedMoves: []models.Move{
+				{Type: models.Movement, Value: 2},
+				{Type: models.Rotation, Value: -1},
+				{Type: models.Movement, Value: 2},
+				{Type: models.Rotation, Value: -1},
+				{Type: models.Movement, Value: 2},
+				{Type: models.Rotation, Value: -1},
+			},
+		},
+		{
+			name:     "Huge repeat count is not expanded",
+			commands: "1000000000000F",
+			expectedMoves: []models.Move{
+				{Type: models.Movement, Value: 1000000000000},
+			},
+		},
+		{
+			name:          "Group that returns to the start",
+			commands:      "1000000(FB)",
+			expectedMoves: []models.Move{},
+		},
+		{
+			name:     "Rotation group folds modulo four",
+			commands: "4(LL)R",
+			expectedMoves: []models.Move{
+				{Type: models.Rotation, Value: -1},
+			},
+		},
+		{
+			name:        "Unclosed group",
+			commands:    "2(FF",
+			expectedErr: models.ErrRouteSyntax,
+		},
+		{
+			name:        "Huge mixed group is not expanded",
+			commands:    "1000000000000(FR)",
+			expectedErr: models.ErrRouteTooLong,
+		},
+		{
+			name:     "Huge group of movements inside mixed group",
+			commands: "2(1000000000000(FFB)R)",
+			expectedMoves: []models.Move{
+				{Type: models.Movement, Value: 1000000000000},
+				{Type: models.Rotation, Value: -1},
+				{Type: models.Movement, Value: 1000000000000},
+				{Type: models.Rotation, Value: -1},
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -115,6 +173,83 @@ func TestOptimizeRoute(t *testing.T) {
 	}
 }
 
+func TestOptimizeRouteWithSpans(t *testing.T) {
+	tests := []struct {
+		name          string
+		commands      string
+		expectedMoves []models.Move
+		expectedSpans []models.Span
+	}{
+		{
+			name:          "Empty route",
+			commands:      "",
+			expectedMoves: []models.Move{},
+			expectedSpans: []models.Span{},
+		},
+		{
+			name:          "Collapsed movement covers all commands",
+			commands:      "FFFBB",
+			expectedMoves: []models.Move{{Type: models.Movement, Value: 1}},
+			expectedSpans: []models.Span{{Start: 0, End: 5}},
+		},
+		{
+			name:     "Movement and rotation",
+			commands: "FFLRRB",
+			expectedMoves: []models.Move{
+				{Type: models.Movement, Value: 2},
+				{Type: models.Rotation, Value: -1},
+				{Type: models.Movement, Value: -1},
+			},
+			expectedSpans: []models.Span{{Start: 0, End: 2}, {Start: 2, End: 5}, {Start: 5, End: 6}},
+		},
+		{
+			name:          "Cancelled rotation is skipped",
+			commands:      "FLRF",
+			expectedMoves: []models.Move{{Type: models.Movement, Value: 1}, {Type: models.Movement, Value: 1}},
+			expectedSpans: []models.Span{{Start: 0, End: 1}, {Start: 3, End: 4}},
+		},
+		{
+			name:          "Repeat count belongs to command",
+			commands:      "10FR",
+			expectedMoves: []models.Move{{Type: models.Movement, Value: 10}, {Type: models.Rotation, Value: -1}},
+			expectedSpans: []models.Span{{Start: 0, End: 3}, {Start: 3, End: 4}},
+		},
+		{
+			name:     "Unrolled group points to commands inside it",
+			commands: "2(FR)",
+			expectedMoves: []models.Move{
+				{Type: models.Movement, Value: 1},
+				{Type: models.Rotation, Value: -1},
+				{Type: models.Movement, Value: 1},
+				{Type: models.Rotation, Value: -1},
+			},
+			expectedSpans: []models.Span{{Start: 2, End: 3}, {Start: 3, End: 4}, {Start: 2, End: 3}, {Start: 3, End: 4}},
+		},
+		{
+			name:          "Folded group covers whole group",
+			commands:      "L1000000(FFB)F",
+			expectedMoves: []models.Move{{Type: models.Rotation, Value: 1}, {Type: models.Movement, Value: 1000001}},
+			expectedSpans: []models.Span{{Start: 0, End: 1}, {Start: 1, End: 14}},
+		},
+		{
+			name:          "Group without effect is not part of span",
+			commands:      "L1000000(FB)F",
+			expectedMoves: []models.Move{{Type: models.Rotation, Value: 1}, {Type: models.Movement, Value: 1}},
+			expectedSpans: []models.Span{{Start: 0, End: 1}, {Start: 12, End: 13}},
+		},
+	}
+
+	optimizer := NewOptimizer()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			moves, spans, err := optimizer.OptimizeRouteWithSpans(tt.commands)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedMoves, moves)
+			assert.Equal(t, tt.expectedSpans, spans)
+		})
+	}
+}
+
 func TestMove(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -0,0 +1,47 @@
+package optimization
+
+import (
+	"fmt"
+	"mars-rover/internal/models"
+	"mars-rover/internal/routelang"
+)
+
+// PassthroughOptimizer не оптимизирует маршрут: каждая команда после раскрытия повторов становится
+// отдельным движением на одну клетку или поворотом на 90 градусов
+type PassthroughOptimizer struct{}
+
+func NewPassthroughOptimizer() *PassthroughOptimizer {
+	return &PassthroughOptimizer{}
+}
+
+func (o *PassthroughOptimizer) OptimizeRoute(commands string) ([]models.Move, error) {
+	moves, _, err := o.OptimizeRouteWithSpans(commands)
+	return moves, err
+}
+
+// OptimizeRouteWithSpans раскрывает маршрут и для каждого движения возвращает диапазон команды в исходной строке
+func (o *PassthroughOptimizer) OptimizeRouteWithSpans(commands string) ([]models.Move, []models.Span, error) {
+	program, err := routelang.Parse(commands)
+	if err != nil {
+		return nil, nil, err
+	}
+	if program.Len() > maxUnrolledLength {
+		return nil, nil, fmt.Errorf("%w to run without optimization: %d commands",
+			models.ErrRouteTooLong, program.Len())
+	}
+
+	moves := make([]models.Move, 0, program.Len())
+	spans := make([]models.Span, 0, program.Len())
+	program.Each(func(command rune, count int, span models.Span) bool {
+		m := models.Move{Type: models.Movement, Value: move(command, 0)}
+		if command == 'L' || command == 'R' {
+			m = models.Move{Type: models.Rotation, Value: rotate(command, 0)}
+		}
+		for i := 0; i < count; i++ {
+			moves = append(moves, m)
+			spans = append(spans, span)
+		}
+		return true
+	})
+	return moves, spans, nil
+}
@@ -0,0 +1,33 @@
+package optimization
+
+import (
+	"mars-rover/internal/models"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPassthroughOptimizer_OptimizeRoute(t *testing.T) {
+	moves, spans, err := NewPassthroughOptimizer().OptimizeRouteWithSpans("FFB2(LR)")
+	require.NoError(t, err)
+
+	assert.Equal(t, []models.Move{
+		{Type: models.Movement, Value: 1},
+		{Type: models.Movement, Value: 1},
+		{Type: models.Movement, Value: -1},
+		{Type: models.Rotation, Value: 1},
+		{Type: models.Rotation, Value: -1},
+		{Type: models.Rotation, Value: 1},
+		{Type: models.Rotation, Value: -1},
+	}, moves)
+	assert.Equal(t, []models.Span{
+		{Start: 0, End: 1}, {Start: 1, End: 2}, {Start: 2, End: 3},
+		{Start: 5, End: 6}, {Start: 6, End: 7}, {Start: 5, End: 6}, {Start: 6, End: 7},
+	}, spans)
+}
+
+func TestPassthroughOptimizer_TooLong(t *testing.T) {
+	_, err := NewPassthroughOptimizer().OptimizeRoute("1000000000000F")
+	assert.ErrorIs(t, err, models.ErrRouteTooLong)
+}
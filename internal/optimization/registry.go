@@ -0,0 +1,167 @@
+package optimization
+
+import (
+	"errors"
+	"fmt"
+	"mars-rover/internal/models"
+	"mars-rover/internal/plateau"
+	"sort"
+	"sync"
+)
+
+var (
+	ErrUnknownStrategy   = errors.New("optimizer error: unknown strategy")
+	ErrDuplicateStrategy = errors.New("optimizer error: strategy is already registered")
+)
+
+// RouteOptimizer оптимизатор маршрута, который создаёт стратегия
+type RouteOptimizer interface {
+	OptimizeRoute(commands string) ([]models.Move, error)
+}
+
+// Config настройки, с которыми стратегия создаёт оптимизатор
+type Config struct {
+	// Plateau плато с границами и препятствиями, nil для бесконечной плоскости
+	Plateau *plateau.Plateau
+	// Start, Direction состояние марсохода, с которого начнётся маршрут
+	Start     models.Coordinates
+	Direction models.Direction
+}
+
+type Option func(*Config)
+
+// WithPlateau задаёт плато для оптимизаторов, которые учитывают препятствия
+func WithPlateau(p *plateau.Plateau) Option {
+	return func(c *Config) {
+		c.Plateau = p
+	}
+}
+
+// WithStart задаёт состояние марсохода, с которого начнётся маршрут
+func WithStart(pos models.Coordinates, dir models.Direction) Option {
+	return func(c *Config) {
+		c.Start = pos
+		c.Direction = dir
+	}
+}
+
+// Strategy именованная стратегия оптимизации
+type Strategy struct {
+	Name        string
+	Description string
+	// Aliases другие названия стратегии
+	Aliases []string
+	// New создаёт оптимизатор с настройками
+	New func(cfg Config) RouteOptimizer
+}
+
+// Registry набор стратегий оптимизации, из которого оптимизатор выбирается по названию
+type Registry struct {
+	mu         sync.RWMutex
+	strategies map[string]Strategy
+	// names названия и псевдонимы стратегий
+	names map[string]string
+}
+
+func NewRegistry() *Registry {
+	return &Registry{strategies: map[string]Strategy{}, names: map[string]string{}}
+}
+
+// Register добавляет стратегию, название и псевдонимы не должны совпадать с уже добавленными
+func (r *Registry) Register(s Strategy) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	names := append([]string{s.Name}, s.Aliases...)
+	for _, name := range names {
+		if _, ok := r.names[name]; ok {
+			return fmt.Errorf("%w: %q", ErrDuplicateStrategy, name)
+		}
+	}
+	for _, name := range names {
+		r.names[name] = s.Name
+	}
+	r.strategies[s.Name] = s
+	return nil
+}
+
+// New создаёт оптимизатор стратегии с названием или псевдонимом name
+func (r *Registry) New(name string, opts ...Option) (RouteOptimizer, error) {
+	r.mu.RLock()
+	s, ok := r.strategies[r.names[name]]
+	r.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownStrategy, name)
+	}
+
+	cfg := Config{Start: models.Coordinates{X: 1, Y: 1}, Direction: models.North}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	return s.New(cfg), nil
+}
+
+// Strategies возвращает стратегии, упорядоченные по названию
+func (r *Registry) Strategies() []Strategy {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	strategies := make([]Strategy, 0, len(r.strategies))
+	for _, s := range r.strategies {
+		strategies = append(strategies, s)
+	}
+	sort.Slice(strategies, func(i, j int) bool { return strategies[i].Name < strategies[j].Name })
+	return strategies
+}
+
+// DefaultStrategy стратегия, которая используется, если другая не выбрана
+const DefaultStrategy = "run-length"
+
+// DefaultRegistry реестр со встроенными стратегиями, в него же можно добавить свои
+var DefaultRegistry = NewRegistry()
+
+func init() {
+	for _, s := range []Strategy{
+		{
+			Name:        "passthrough",
+			Description: "без оптимизации, каждая команда выполняется отдельно",
+			Aliases:     []string{"none"},
+			New:         func(Config) RouteOptimizer { return NewPassthroughOptimizer() },
+		},
+		{
+			Name:        DefaultStrategy,
+			Description: "схлопывает подряд идущие движения и подряд идущие повороты",
+			Aliases:     []string{"runs"},
+			New:         func(Config) RouteOptimizer { return NewOptimizer() },
+		},
+		{
+			Name:        "minimal",
+			Description: "кратчайший маршрут в ту же точку и направление без учёта препятствий",
+			New:         func(Config) RouteOptimizer { return NewMinimalOptimizer() },
+		},
+		{
+			Name:        "obstacle-aware",
+			Description: "схлопывает только отрезки, которые не заезжают в препятствия и за край плато",
+			Aliases:     []string{"safe"},
+			New: func(cfg Config) RouteOptimizer {
+				o := NewSafeOptimizer(cfg.Plateau)
+				o.SetStart(cfg.Start, cfg.Direction)
+				return o
+			},
+		},
+	} {
+		if err := DefaultRegistry.Register(s); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// Register добавляет стратегию в DefaultRegistry
+func Register(s Strategy) error {
+	return DefaultRegistry.Register(s)
+}
+
+// New создаёт оптимизатор стратегии name из DefaultRegistry
+func New(name string, opts ...Option) (RouteOptimizer, error) {
+	return DefaultRegistry.New(name, opts...)
+}
@@ -0,0 +1,69 @@
+package optimization
+
+import (
+	"mars-rover/internal/models"
+	"mars-rover/internal/plateau"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDefaultRegistry(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected RouteOptimizer
+	}{
+		{name: "passthrough", expected: &PassthroughOptimizer{}},
+		{name: "none", expected: &PassthroughOptimizer{}},
+		{name: "run-length", expected: &Optimizer{}},
+		{name: "runs", expected: &Optimizer{}},
+		{name: "minimal", expected: &MinimalOptimizer{}},
+		{name: "obstacle-aware", expected: &SafeOptimizer{}},
+		{name: "safe", expected: &SafeOptimizer{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			optimizer, err := New(tt.name)
+			require.NoError(t, err)
+			assert.IsType(t, tt.expected, optimizer)
+		})
+	}
+
+	names := []string{}
+	for _, s := range DefaultRegistry.Strategies() {
+		names = append(names, s.Name)
+	}
+	assert.Equal(t, []string{"minimal", "obstacle-aware", "passthrough", "run-length"}, names)
+}
+
+func TestRegistry_Options(t *testing.T) {
+	p := &plateau.Plateau{Width: 5, Height: 5}
+	optimizer, err := New("obstacle-aware",
+		WithPlateau(p), WithStart(models.Coordinates{X: 2, Y: 3}, models.East))
+	require.NoError(t, err)
+
+	safe := optimizer.(*SafeOptimizer)
+	assert.Equal(t, p, safe.Plateau)
+	assert.Equal(t, models.Coordinates{X: 2, Y: 3}, safe.Start)
+	assert.Equal(t, models.East, safe.Direction)
+}
+
+func TestRegistry_Register(t *testing.T) {
+	registry := NewRegistry()
+	custom := Strategy{
+		Name:    "custom",
+		Aliases: []string{"mine"},
+		New:     func(Config) RouteOptimizer { return NewMinimalOptimizer() },
+	}
+	require.NoError(t, registry.Register(custom))
+
+	optimizer, err := registry.New("mine")
+	require.NoError(t, err)
+	assert.IsType(t, &MinimalOptimizer{}, optimizer)
+
+	assert.ErrorIs(t, registry.Register(Strategy{Name: "mine"}), ErrDuplicateStrategy)
+	_, err = registry.New("minimal")
+	assert.ErrorIs(t, err, ErrUnknownStrategy)
+}
@@ -0,0 +1,274 @@
+package optimization
+
+import (
+	"fmt"
+	"mars-rover/internal/models"
+	"mars-rover/internal/plateau"
+	"mars-rover/internal/routelang"
+	"mars-rover/internal/rover"
+	"strconv"
+	"strings"
+)
+
+// maxUnrolledLength ограничение на длину раскрытого маршрута для оптимизаторов, которые перебирают каждую команду
+const maxUnrolledLength = 1 << 20
+
+// SafeOptimizer оптимизатор, который знает о плато и препятствиях.
+// Отрезок из однотипных команд схлопывается, только если схлопнутое движение не заезжает в клетки,
+// которые исходный маршрут не посещал, и приводит марсоход в то же состояние
+type SafeOptimizer struct {
+	Plateau   *plateau.Plateau
+	Start     models.Coordinates
+	Direction models.Direction
+
+	report Report
+}
+
+// Merge запись отчёта об одном отрезке маршрута из однотипных команд
+type Merge struct {
+	// Start, End границы отрезка в строке команд, End не включается
+	Start int
+	End   int
+	// Commands исходные команды отрезка
+	Commands string
+	// Moves движения, в которые превратился отрезок
+	Moves []models.Move
+	// Merged признак того, что отрезок схлопнут целиком
+	Merged bool
+}
+
+// Report отчёт о том, какие отрезки маршрута были схлопнуты
+type Report struct {
+	Merges []Merge
+}
+
+func NewSafeOptimizer(p *plateau.Plateau) *SafeOptimizer {
+	return &SafeOptimizer{
+		Plateau:   p,
+		Start:     models.Coordinates{X: 1, Y: 1},
+		Direction: models.North,
+	}
+}
+
+// SetStart задаёт состояние марсохода, с которого начнётся маршрут
+func (o *SafeOptimizer) SetStart(pos models.Coordinates, dir models.Direction) {
+	o.Start = pos
+	o.Direction = dir
+}
+
+// LastReport возвращает отчёт о последней оптимизации
+func (o *SafeOptimizer) LastReport() Report {
+	return o.report
+}
+
+func (o *SafeOptimizer) OptimizeRoute(commands string) ([]models.Move, error) {
+	moves, _, err := o.OptimizeRouteWithSpans(commands)
+	return moves, err
+}
+
+// OptimizeRouteWithSpans оптимизирует маршрут как OptimizeRoute и для каждого движения возвращает диапазон
+// исходной строки, из которого оно получено
+func (o *SafeOptimizer) OptimizeRouteWithSpans(commands string) ([]models.Move, []models.Span, error) {
+	moves, spans, report, err := o.optimize(commands)
+	if err != nil {
+		return nil, nil, err
+	}
+	o.report = report
+	return moves, spans, nil
+}
+
+// OptimizeRouteWithReport оптимизирует маршрут, проигрывая его на плато с начального состояния.
+// Повторы и группы раскрываются, границы отрезков в отчёте указывают на раскрытый маршрут.
+// Если исходный маршрут упирается в препятствие или край плато, дальнейшие отрезки не проверяются:
+// марсоход всё равно остановится там же, где остановился бы на исходном маршруте
+func (o *SafeOptimizer) OptimizeRouteWithReport(commands string) ([]models.Move, Report, error) {
+	moves, _, report, err := o.optimize(commands)
+	return moves, report, err
+}
+
+func (o *SafeOptimizer) optimize(commands string) ([]models.Move, []models.Span, Report, error) {
+	program, err := routelang.Parse(commands)
+	if err != nil {
+		return nil, nil, Report{}, err
+	}
+	if program.Len() > maxUnrolledLength {
+		return nil, nil, Report{}, fmt.Errorf("%w for the safe optimizer: %d commands",
+			models.ErrRouteTooLong, program.Len())
+	}
+	// sources диапазон исходной строки для каждой команды раскрытого маршрута
+	var expanded strings.Builder
+	sources := make([]models.Span, 0, program.Len())
+	program.Each(func(command rune, count int, span models.Span) bool {
+		expanded.WriteString(strings.Repeat(string(command), count))
+		for i := 0; i < count; i++ {
+			sources = append(sources, span)
+		}
+		return true
+	})
+	commands = expanded.String()
+
+	sim := rover.Rover{Pos: o.Start, Direction: o.Direction, Plateau: o.Plateau}
+	stopped := false
+	moves := make([]models.Move, 0, len(commands))
+	spans := make([]models.Span, 0, len(commands))
+	report := Report{}
+
+	for start := 0; start < len(commands); {
+		end := start + 1
+		for end < len(commands) && isMovement(commands[end]) == isMovement(commands[start]) {
+			end++
+		}
+		run := commands[start:end]
+
+		merge := Merge{Start: start, End: end, Commands: run}
+		switch {
+		case !isMovement(run[0]):
+			merge.Moves, merge.Merged = mergeTurns(run), true
+			for _, m := range merge.Moves {
+				sim.Rotate(m.Value)
+			}
+		case stopped:
+			merge.Moves = monotonicRuns(run)
+		default:
+			merge.Moves, merge.Merged, stopped = mergeSteps(&sim, run)
+		}
+
+		moves = append(moves, merge.Moves...)
+		spans = append(spans, runSpans(run, sources[start:end], len(merge.Moves), merge.Merged)...)
+		report.Merges = append(report.Merges, merge)
+		start = end
+	}
+
+	return moves, spans, report, nil
+}
+
+// runSpans возвращает диапазоны исходной строки для движений, в которые превратился отрезок run:
+// схлопнутый отрезок получен из всех своих команд, иначе каждое движение получено из серии одинаковых команд,
+// как в monotonicRuns
+func runSpans(run string, sources []models.Span, moves int, merged bool) []models.Span {
+	if moves == 0 {
+		return nil
+	}
+	if merged {
+		var span models.Span
+		for _, source := range sources {
+			span = span.Union(source)
+		}
+		return []models.Span{span}
+	}
+
+	spans := make([]models.Span, 0, moves)
+	for i := 0; i < len(run); {
+		var span models.Span
+		j := i
+		for ; j < len(run) && run[j] == run[i]; j++ {
+			span = span.Union(sources[j])
+		}
+		spans = append(spans, span)
+		i = j
+	}
+	return spans
+}
+
+// mergeSteps проигрывает отрезок из команд F и B шаг за шагом и схлопывает его в одно движение,
+// если схлопнутое движение посещает только клетки исходного отрезка и заканчивается в той же клетке
+func mergeSteps(sim *rover.Rover, run string) ([]models.Move, bool, bool) {
+	original := *sim
+	visited := map[models.Coordinates]struct{}{original.Pos: {}}
+	net := 0
+	for i := 0; i < len(run); i++ {
+		step := move(rune(run[i]), 0)
+		net += step
+		if err := original.Move(step); err != nil {
+			*sim = original
+			return monotonicRuns(run), false, true
+		}
+		visited[original.Pos] = struct{}{}
+	}
+
+	merged := *sim
+	safe := true
+	for i := 0; i < abs(net) && safe; i++ {
+		err := merged.Move(sign(net))
+		_, seen := visited[merged.Pos]
+		safe = err == nil && seen
+	}
+	*sim = original
+
+	if !safe || merged.Pos != original.Pos {
+		return monotonicRuns(run), false, false
+	}
+	if net == 0 {
+		return nil, true, false
+	}
+	return []models.Move{{Type: models.Movement, Value: net}}, true, false
+}
+
+// mergeTurns схлопывает отрезок из команд L и R в один поворот
+func mergeTurns(run string) []models.Move {
+	turns := 0
+	for _, command := range run {
+		turns = rotate(command, turns)
+	}
+	if turns%4 == 0 {
+		return nil
+	}
+	return []models.Move{{Type: models.Rotation, Value: turns % 4}}
+}
+
+// monotonicRuns схлопывает только подряд идущие одинаковые команды движения: FFFBB => 3F, 2B.
+// Такие движения посещают ровно те же клетки, что и исходные команды, при любом поведении на краю плато:
+// ход, упёршийся в край или препятствие, заканчивается в той же клетке, что и команды по одной
+func monotonicRuns(run string) []models.Move {
+	var moves []models.Move
+	for i := 0; i < len(run); {
+		j := i
+		for j < len(run) && run[j] == run[i] {
+			j++
+		}
+		moves = append(moves, models.Move{Type: models.Movement, Value: move(rune(run[i]), 0) * (j - i)})
+		i = j
+	}
+	return moves
+}
+
+func isMovement(command byte) bool {
+	return command == 'F' || command == 'B'
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+func sign(v int) int {
+	if v < 0 {
+		return -1
+	}
+	return 1
+}
+
+// FormatMoves записывает движения в виде команд: Movement 3 => 3F, Rotation -1 => R
+func FormatMoves(moves []models.Move) string {
+	var b strings.Builder
+	for _, m := range moves {
+		var command string
+		switch {
+		case m.Type == models.Movement && m.Value >= 0:
+			command = "F"
+		case m.Type == models.Movement:
+			command = "B"
+		case m.Value >= 0:
+			command = "L"
+		default:
+			command = "R"
+		}
+		if count := abs(m.Value); count != 1 {
+			b.WriteString(strconv.Itoa(count))
+		}
+		b.WriteString(command)
+	}
+	return b.String()
+}
@@ -0,0 +1,196 @@
+package optimization
+
+import (
+	"mars-rover/internal/models"
+	"mars-rover/internal/plateau"
+	"mars-rover/internal/rover"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSafeOptimizer_OptimizeRoute(t *testing.T) {
+	obstacles := plateau.NewObstacles(models.Coordinates{X: 1, Y: 4})
+
+	tests := []struct {
+		name          string
+		plateau       *plateau.Plateau
+		start         models.Coordinates
+		commands      string
+		expectedMoves []models.Move
+		expectedErr   error
+	}{
+		{
+			name:          "Free plane collapses back and forth",
+			start:         models.Coordinates{X: 1, Y: 1},
+			commands:      "FFFBBB",
+			expectedMoves: []models.Move{},
+		},
+		{
+			name:     "Original route hits obstacle",
+			plateau:  &plateau.Plateau{Obstacles: obstacles},
+			start:    models.Coordinates{X: 1, Y: 1},
+			commands: "FFFBBB",
+			expectedMoves: []models.Move{
+				{Type: models.Movement, Value: 3},
+				{Type: models.Movement, Value: -3},
+			},
+		},
+		{
+			name:     "Merged move stays on visited cells",
+			plateau:  &plateau.Plateau{Obstacles: obstacles},
+			start:    models.Coordinates{X: 1, Y: 1},
+			commands: "FFBLLLLRF",
+			expectedMoves: []models.Move{
+				{Type: models.Movement, Value: 1},
+				{Type: models.Rotation, Value: 3},
+				{Type: models.Movement, Value: 1},
+			},
+		},
+		{
+			name:     "Clamped route is not merged",
+			plateau:  &plateau.Plateau{Width: 5, Height: 5, Edge: plateau.EdgeClamp},
+			start:    models.Coordinates{X: 1, Y: 3},
+			commands: "FFFBB",
+			expectedMoves: []models.Move{
+				{Type: models.Movement, Value: 3},
+				{Type: models.Movement, Value: -2},
+			},
+		},
+		{
+			name:        "Invalid command",
+			start:       models.Coordinates{X: 1, Y: 1},
+			commands:    "FFX",
+			expectedErr: models.ErrIncorrectSymbol,
+		},
+		{
+			name:     "Repeat counts are expanded before merging",
+			plateau:  &plateau.Plateau{Obstacles: obstacles},
+			start:    models.Coordinates{X: 1, Y: 1},
+			commands: "3F3B",
+			expectedMoves: []models.Move{
+				{Type: models.Movement, Value: 3},
+				{Type: models.Movement, Value: -3},
+			},
+		},
+		{
+			name:        "Route is too long to replay",
+			start:       models.Coordinates{X: 1, Y: 1},
+			commands:    "1000000000F",
+			expectedErr: models.ErrRouteTooLong,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			optimizer := NewSafeOptimizer(tt.plateau)
+			optimizer.SetStart(tt.start, models.North)
+			moves, err := optimizer.OptimizeRoute(tt.commands)
+
+			if tt.expectedErr != nil {
+				assert.ErrorIs(t, err, tt.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedMoves, moves)
+		})
+	}
+}
+
+func TestSafeOptimizer_Report(t *testing.T) {
+	optimizer := NewSafeOptimizer(&plateau.Plateau{Width: 5, Height: 5, Edge: plateau.EdgeClamp})
+	optimizer.SetStart(models.Coordinates{X: 1, Y: 3}, models.North)
+
+	_, err := optimizer.OptimizeRoute("FFFBBRRFB")
+	require.NoError(t, err)
+
+	assert.Equal(t, Report{Merges: []Merge{
+		{
+			Start: 0, End: 5, Commands: "FFFBB",
+			Moves:  []models.Move{{Type: models.Movement, Value: 3}, {Type: models.Movement, Value: -2}},
+			Merged: false,
+		},
+		{
+			Start: 5, End: 7, Commands: "RR",
+			Moves:  []models.Move{{Type: models.Rotation, Value: -2}},
+			Merged: true,
+		},
+		{
+			Start: 7, End: 9, Commands: "FB",
+			Merged: true,
+		},
+	}}, optimizer.LastReport())
+}
+
+func TestSafeOptimizer_Spans(t *testing.T) {
+	optimizer := NewSafeOptimizer(&plateau.Plateau{Width: 5, Height: 5, Edge: plateau.EdgeClamp})
+	optimizer.SetStart(models.Coordinates{X: 1, Y: 3}, models.North)
+
+	moves, spans, err := optimizer.OptimizeRouteWithSpans("FFFBBRR2(FB)L")
+	require.NoError(t, err)
+
+	assert.Equal(t, []models.Move{
+		{Type: models.Movement, Value: 3},
+		{Type: models.Movement, Value: -2},
+		{Type: models.Rotation, Value: -2},
+		{Type: models.Rotation, Value: 1},
+	}, moves)
+	assert.Equal(t, []models.Span{{Start: 0, End: 3}, {Start: 3, End: 5}, {Start: 5, End: 7}, {Start: 12, End: 13}}, spans)
+	assert.Len(t, optimizer.LastReport().Merges, 4)
+}
+
+// TestSafeOptimizer_Equivalence проверяет, что оптимизированный маршрут приводит марсоход
+// в то же состояние, что и исходный маршрут, выполненный по одной команде, при любом поведении на краю плато
+func TestSafeOptimizer_Equivalence(t *testing.T) {
+	routes := []string{
+		"FFFFBBBB", "FFRFFFBBLF", "LFFFFFFBBBRBBBB", "RFFBFFBBBLFFFF", "BBBBBFFFFF",
+		"FFFFFFFF", "RFFFFFFFLBBB", "FFFFFFBBRFFFFFFF",
+	}
+
+	for _, edge := range []plateau.EdgeMode{plateau.EdgeReject, plateau.EdgeClamp, plateau.EdgeWrap} {
+		p := &plateau.Plateau{
+			Width: 6, Height: 6, Edge: edge,
+			Obstacles: plateau.NewObstacles(models.Coordinates{X: 3, Y: 3}, models.Coordinates{X: 0, Y: 5}),
+		}
+		for _, route := range routes {
+			t.Run(string(edge)+"/"+route, func(t *testing.T) {
+				optimizer := NewSafeOptimizer(p)
+				moves, err := optimizer.OptimizeRoute(route)
+				require.NoError(t, err)
+
+				optimized := rover.Rover{Pos: optimizer.Start, Direction: optimizer.Direction, Plateau: p}
+				optimizedErr := optimized.PerformRoute(moves)
+
+				original := rover.Rover{Pos: optimizer.Start, Direction: optimizer.Direction, Plateau: p}
+				var originalErr error
+				for _, command := range route {
+					switch command {
+					case 'F', 'B':
+						originalErr = original.Move(move(command, 0))
+					default:
+						original.Rotate(rotate(command, 0))
+					}
+					if originalErr != nil {
+						break
+					}
+				}
+
+				assert.Equal(t, original.Pos, optimized.Pos)
+				assert.Equal(t, original.Direction, optimized.Direction)
+				assert.Equal(t, originalErr != nil, optimizedErr != nil)
+			})
+		}
+	}
+}
+
+func TestFormatMoves(t *testing.T) {
+	moves := []models.Move{
+		{Type: models.Movement, Value: 3},
+		{Type: models.Rotation, Value: 1},
+		{Type: models.Movement, Value: -1},
+		{Type: models.Rotation, Value: -2},
+	}
+	assert.Equal(t, "3FLB2R", FormatMoves(moves))
+	assert.Equal(t, "", FormatMoves(nil))
+}
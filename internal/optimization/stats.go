@@ -0,0 +1,55 @@
+package optimization
+
+import (
+	"mars-rover/internal/models"
+	"mars-rover/internal/routelang"
+)
+
+// Stats сравнение исходного маршрута с оптимизированным
+type Stats struct {
+	// Commands количество команд исходного маршрута после раскрытия повторов
+	Commands int
+	// Moves количество движений оптимизированного маршрута
+	Moves int
+	// Distance, Turns клетки и повороты на 90 градусов, которые требует исходный маршрут
+	Distance int
+	Turns    int
+	// OptimizedDistance, OptimizedTurns клетки и повороты на 90 градусов оптимизированного маршрута
+	OptimizedDistance int
+	OptimizedTurns    int
+}
+
+// DistanceSaved сколько клеток сэкономил оптимизатор
+func (s Stats) DistanceSaved() int {
+	return s.Distance - s.OptimizedDistance
+}
+
+// TurnsSaved сколько поворотов на 90 градусов сэкономил оптимизатор
+func (s Stats) TurnsSaved() int {
+	return s.Turns - s.OptimizedTurns
+}
+
+// NewStats сравнивает строку команд с движениями, в которые её превратил оптимизатор.
+// Повторы не перебираются, поэтому статистика считается и для маршрутов вида 1000000000000F
+func NewStats(commands string, moves []models.Move) (Stats, error) {
+	program, err := routelang.Parse(commands)
+	if err != nil {
+		return Stats{}, err
+	}
+
+	stats := Stats{
+		Commands: program.Len(),
+		Moves:    len(moves),
+		Distance: program.Count("FB"),
+		Turns:    program.Count("LR"),
+	}
+	for _, m := range moves {
+		switch m.Type {
+		case models.Movement:
+			stats.OptimizedDistance += abs(m.Value)
+		case models.Rotation:
+			stats.OptimizedTurns += abs(m.Value)
+		}
+	}
+	return stats, nil
+}
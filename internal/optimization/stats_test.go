@@ -0,0 +1,63 @@
+package optimization
+
+import (
+	"mars-rover/internal/models"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewStats(t *testing.T) {
+	tests := []struct {
+		name          string
+		commands      string
+		expected      Stats
+		distanceSaved int
+		turnsSaved    int
+	}{
+		{
+			name:     "Empty route",
+			commands: "",
+			expected: Stats{},
+		},
+		{
+			name:          "Back and forth collapsed",
+			commands:      "FFFBB",
+			expected:      Stats{Commands: 5, Moves: 1, Distance: 5, OptimizedDistance: 1},
+			distanceSaved: 4,
+		},
+		{
+			name:       "Turns collapsed",
+			commands:   "FLLLRB",
+			expected:   Stats{Commands: 6, Moves: 3, Distance: 2, Turns: 4, OptimizedDistance: 2, OptimizedTurns: 2},
+			turnsSaved: 2,
+		},
+		{
+			name:          "Huge repeat is not expanded",
+			commands:      "1000000000000(FB)4R",
+			expected:      Stats{Commands: 2000000000004, Distance: 2000000000000, Turns: 4},
+			distanceSaved: 2000000000000,
+			turnsSaved:    4,
+		},
+	}
+
+	optimizer := NewOptimizer()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			moves, err := optimizer.OptimizeRoute(tt.commands)
+			require.NoError(t, err)
+
+			stats, err := NewStats(tt.commands, moves)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, stats)
+			assert.Equal(t, tt.distanceSaved, stats.DistanceSaved())
+			assert.Equal(t, tt.turnsSaved, stats.TurnsSaved())
+		})
+	}
+}
+
+func TestNewStatsInvalidRoute(t *testing.T) {
+	_, err := NewStats("2(FF", []models.Move{})
+	assert.ErrorIs(t, err, models.ErrRouteSyntax)
+}
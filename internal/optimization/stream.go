@@ -0,0 +1,117 @@
+package optimization
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"mars-rover/internal/models"
+	"unicode/utf8"
+)
+
+// maxStreamLength ограничение на длину потока команд после раскрытия повторов,
+// чтобы суммарное количество шагов и поворотов гарантированно помещалось в int
+const maxStreamLength = 1 << 62
+
+// OptimizeStream читает команды из r и схлопывает их так же, как OptimizeRoute, передавая каждое движение
+// в emit, как только оно собрано. Память не зависит от длины потока, поэтому так можно выполнять
+// многогигабайтные журналы команд. Поток состоит из команд F, B, L, R с необязательным количеством повторов: 10F.
+// Пробелы и переводы строк пропускаются, всё от # до конца строки считается комментарием. Группы не
+// поддерживаются: чтобы повторить группу, её пришлось бы держать в памяти. Движения, собранные до ошибки
+// во входных данных, передаются в emit до возврата ошибки. Ошибка emit прерывает чтение
+func OptimizeStream(r io.Reader, emit func(m models.Move, span models.Span) error) error {
+	var emitErr error
+	b := &builder{emit: func(m models.Move, span models.Span) {
+		if emitErr == nil {
+			emitErr = emit(m, span)
+		}
+	}}
+
+	err := scan(bufio.NewReader(r), func(command rune, count int, span models.Span) bool {
+		b.add(command, count, span)
+		return emitErr == nil
+	})
+	// серия команд, собранная до ошибки во входных данных, тоже передаётся в emit
+	if emitErr == nil {
+		b.finish()
+	}
+	if emitErr != nil {
+		return emitErr
+	}
+	return err
+}
+
+// ScanStream читает команды из r так же, как OptimizeStream, но не схлопывает их: каждая команда передаётся в yield
+// с количеством повторов и диапазоном в потоке. Чтение прекращается, если yield вернул false
+func ScanStream(r io.Reader, yield func(command rune, count int, span models.Span) bool) error {
+	return scan(bufio.NewReader(r), yield)
+}
+
+// scan читает команды из reader и передаёт их в yield, пока он возвращает true
+func scan(reader *bufio.Reader, yield func(command rune, count int, span models.Span) bool) error {
+	offset, total := 0, 0
+	// count количество повторов перед командой, countAt его смещение, -1 если количества нет
+	count, countAt := 0, -1
+	for {
+		c, err := reader.ReadByte()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case c >= '0' && c <= '9':
+			if countAt < 0 {
+				count, countAt = 0, offset
+			}
+			if count > (maxStreamLength-int(c-'0'))/10 {
+				return fmt.Errorf("%w: repeat count at offset %d is too large", models.ErrRouteSyntax, countAt)
+			}
+			count = count*10 + int(c-'0')
+		case c == 'F' || c == 'B' || c == 'L' || c == 'R':
+			start, repeat := offset, 1
+			if countAt >= 0 {
+				start, repeat = countAt, count
+			}
+			if repeat > maxStreamLength-total {
+				return fmt.Errorf("%w: route is too long at offset %d", models.ErrRouteSyntax, start)
+			}
+			total += repeat
+			countAt = -1
+			if !yield(rune(c), repeat, models.Span{Start: start, End: offset + 1}) {
+				return nil
+			}
+		case c == '(' || c == ')':
+			return fmt.Errorf("%w: groups are not supported in streamed routes, got %q at offset %d",
+				models.ErrRouteSyntax, c, offset)
+		case countAt >= 0:
+			return fmt.Errorf("%w: repeat count at offset %d is not followed by a command", models.ErrRouteSyntax, countAt)
+		case c == '#':
+			line, err := reader.ReadSlice('\n')
+			for errors.Is(err, bufio.ErrBufferFull) {
+				offset += len(line)
+				line, err = reader.ReadSlice('\n')
+			}
+			offset += len(line)
+			if err != nil && !errors.Is(err, io.EOF) {
+				return err
+			}
+		case c == ' ' || c == '\t' || c == '\r' || c == '\n':
+		default:
+			symbol := rune(c)
+			if c >= utf8.RuneSelf {
+				_ = reader.UnreadByte()
+				symbol, _, _ = reader.ReadRune()
+			}
+			// поток не хранится, поэтому фрагмент маршрута вокруг символа - сам символ
+			return models.SymbolErrors{{Offset: offset, Symbol: symbol, Context: string(symbol)}}
+		}
+		offset++
+	}
+	if countAt >= 0 {
+		return fmt.Errorf("%w: repeat count at offset %d is not followed by a command", models.ErrRouteSyntax, countAt)
+	}
+	return nil
+}
@@ -0,0 +1,201 @@
+package optimization
+
+import (
+	"errors"
+	"mars-rover/internal/models"
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func collect(t *testing.T, input string) ([]models.Move, []models.Span, error) {
+	t.Helper()
+	moves, spans := []models.Move{}, []models.Span{}
+	err := OptimizeStream(strings.NewReader(input), func(m models.Move, span models.Span) error {
+		moves = append(moves, m)
+		spans = append(spans, span)
+		return nil
+	})
+	return moves, spans, err
+}
+
+func TestOptimizeStream(t *testing.T) {
+	tests := []struct {
+		name          string
+		input         string
+		expectedMoves []models.Move
+		expectedSpans []models.Span
+	}{
+		{
+			name:          "Empty stream",
+			input:         "",
+			expectedMoves: []models.Move{},
+			expectedSpans: []models.Span{},
+		},
+		{
+			name:          "Collapsed movement",
+			input:         "FFFBB",
+			expectedMoves: []models.Move{{Type: models.Movement, Value: 1}},
+			expectedSpans: []models.Span{{Start: 0, End: 5}},
+		},
+		{
+			name:  "Lines, comments and repeat counts",
+			input: "# журнал\n10F 2B\r\nLLL # разворот\n1000000000000R\n",
+			expectedMoves: []models.Move{
+				{Type: models.Movement, Value: 8},
+				{Type: models.Rotation, Value: -1},
+			},
+			expectedSpans: []models.Span{{Start: 15, End: 21}, {Start: 23, End: 60}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			moves, spans, err := collect(t, tt.input)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedMoves, moves)
+			assert.Equal(t, tt.expectedSpans, spans)
+		})
+	}
+}
+
+// TestOptimizeStream_Equivalence проверяет, что поток схлопывается так же, как строка команд
+func TestOptimizeStream_Equivalence(t *testing.T) {
+	random := rand.New(rand.NewSource(19))
+	for i := 0; i < 100; i++ {
+		var b strings.Builder
+		for j := random.Intn(50); j > 0; j-- {
+			if random.Intn(5) == 0 {
+				b.WriteString("12")
+			}
+			b.WriteByte("FBLR"[random.Intn(4)])
+		}
+		route := b.String()
+
+		expectedMoves, expectedSpans, err := NewOptimizer().OptimizeRouteWithSpans(route)
+		require.NoError(t, err)
+		moves, spans, err := collect(t, route)
+		require.NoError(t, err)
+		assert.Equal(t, expectedMoves, moves, "route %s", route)
+		assert.Equal(t, expectedSpans, spans, "route %s", route)
+	}
+}
+
+func TestOptimizeStream_Errors(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected error
+		message  string
+	}{
+		{
+			name:     "Invalid symbol",
+			input:    "FF\nFЖF",
+			expected: models.ErrIncorrectSymbol,
+			message:  "validation error: unexpected input: 'Ж' at offset 4",
+		},
+		{
+			name:     "Invalid symbol after comments",
+			input:    "# разведка\nFF  # вперёд\n\nL R\nB\nFXF\n",
+			expected: models.ErrIncorrectSymbol,
+			message:  "validation error: unexpected input: 'X' at offset 46",
+		},
+		{
+			name:     "Macro definition",
+			input:    "def square = 4(FFR)\nsquare\nF\n",
+			expected: models.ErrIncorrectSymbol,
+			message:  "validation error: unexpected input: 'd' at offset 0",
+		},
+		{
+			name:     "Group",
+			input:    "2(FF)",
+			expected: models.ErrRouteSyntax,
+			message:  "syntax error: malformed route: groups are not supported in streamed routes, got '(' at offset 1",
+		},
+		{
+			name:     "Dangling repeat count",
+			input:    "FF 12 F",
+			expected: models.ErrRouteSyntax,
+			message:  "syntax error: malformed route: repeat count at offset 3 is not followed by a command",
+		},
+		{
+			name:     "Repeat count at the end",
+			input:    "FF12",
+			expected: models.ErrRouteSyntax,
+			message:  "syntax error: malformed route: repeat count at offset 2 is not followed by a command",
+		},
+		{
+			name:     "Route too long",
+			input:    "4611686018427387904F F",
+			expected: models.ErrRouteSyntax,
+			message:  "syntax error: malformed route: route is too long at offset 21",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := collect(t, tt.input)
+			assert.ErrorIs(t, err, tt.expected)
+			assert.EqualError(t, err, tt.message)
+		})
+	}
+}
+
+func TestOptimizeStream_MovesBeforeError(t *testing.T) {
+	moves, spans, err := collect(t, "FFL\nLBX")
+	assert.ErrorIs(t, err, models.ErrIncorrectSymbol)
+	assert.Equal(t, []models.Move{
+		{Type: models.Movement, Value: 2},
+		{Type: models.Rotation, Value: 2},
+		{Type: models.Movement, Value: -1},
+	}, moves)
+	assert.Equal(t, []models.Span{{Start: 0, End: 2}, {Start: 2, End: 5}, {Start: 5, End: 6}}, spans)
+}
+
+func TestOptimizeStream_EmitError(t *testing.T) {
+	stop := errors.New("stop")
+	emitted := 0
+	err := OptimizeStream(strings.NewReader("FLFLFLFL"), func(models.Move, models.Span) error {
+		emitted++
+		return stop
+	})
+	assert.ErrorIs(t, err, stop)
+	assert.Equal(t, 1, emitted)
+}
+
+// benchmarkRoute маршрут из 1<<20 команд, в котором серии движений чередуются с поворотами
+func benchmarkRoute() string {
+	random := rand.New(rand.NewSource(1))
+	route := make([]byte, 1<<20)
+	for i := range route {
+		route[i] = "FFFBLR"[random.Intn(6)]
+	}
+	return string(route)
+}
+
+func BenchmarkOptimizeRoute(b *testing.B) {
+	route := benchmarkRoute()
+	optimizer := NewOptimizer()
+	b.ReportAllocs()
+	b.SetBytes(int64(len(route)))
+	for i := 0; i < b.N; i++ {
+		if _, err := optimizer.OptimizeRoute(route); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkOptimizeStream(b *testing.B) {
+	route := benchmarkRoute()
+	b.ReportAllocs()
+	b.SetBytes(int64(len(route)))
+	for i := 0; i < b.N; i++ {
+		err := OptimizeStream(strings.NewReader(route), func(models.Move, models.Span) error { return nil })
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
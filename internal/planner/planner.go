@@ -0,0 +1,176 @@
+package planner
+
+import (
+	"errors"
+	"fmt"
+	"mars-rover/internal/models"
+	"mars-rover/internal/plateau"
+	"mars-rover/internal/rover"
+	"strings"
+)
+
+// maxCells ограничение на количество клеток области поиска
+const maxCells = 1 << 20
+
+var (
+	ErrUnreachable = errors.New("planner error: target is unreachable")
+	ErrSearchLimit = errors.New("planner error: search area is too large")
+)
+
+// State состояние марсохода: клетка и направление
+type State struct {
+	Pos       models.Coordinates
+	Direction models.Direction
+}
+
+// commands команды, из которых планировщик собирает маршрут, в порядке перебора
+var commands = []byte{'F', 'B', 'L', 'R'}
+
+// directions все направления марсохода, номер направления используется в индексе состояния
+var directions = []models.Direction{models.North, models.West, models.South, models.East}
+
+// Plan ищет кратчайшую строку команд F, B, L, R, которая приводит марсоход из start в клетку target.
+// heading направление марсохода в цели, пустое, если направление не важно. p плато с границами и препятствиями,
+// nil для бесконечной плоскости. Поиск в ширину идёт по состояниям (x, y, направление), каждая команда
+// проигрывается марсоходом на плато, поэтому учитываются препятствия и поведение на краю плато.
+// По неограниченной оси поиск не выходит за клетки старта, цели и препятствий больше чем на одну клетку:
+// дальше препятствий нет, и обходить там нечего
+func Plan(p *plateau.Plateau, start State, target models.Coordinates, heading models.Direction) (string, error) {
+	if p == nil {
+		p = &plateau.Plateau{}
+	}
+	if !p.Contains(target) || p.Blocked(target) {
+		return "", fmt.Errorf("%w: (%d, %d) is outside the plateau or blocked", ErrUnreachable, target.X, target.Y)
+	}
+
+	g, err := newGrid(p, start.Pos, target)
+	if err != nil {
+		return "", err
+	}
+	first, ok := g.index(start)
+	if !ok {
+		return "", fmt.Errorf("%w: start (%d, %d) is outside the plateau", ErrUnreachable, start.Pos.X, start.Pos.Y)
+	}
+
+	// parent для каждого посещённого состояния хранит предыдущее состояние и команду, -1 для непосещённых
+	parent := make([]int, g.size())
+	command := make([]byte, g.size())
+	for i := range parent {
+		parent[i] = -1
+	}
+	parent[first] = first
+
+	queue := []int{first}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		state := g.state(current)
+		if state.Pos == target && (heading == "" || state.Direction == heading) {
+			return path(parent, command, current), nil
+		}
+
+		for _, c := range commands {
+			next, ok := step(p, state, c)
+			if !ok {
+				continue
+			}
+			i, ok := g.index(next)
+			if !ok || parent[i] >= 0 {
+				continue
+			}
+			parent[i], command[i] = current, c
+			queue = append(queue, i)
+		}
+	}
+	return "", fmt.Errorf("%w: no route from (%d, %d) to (%d, %d)",
+		ErrUnreachable, start.Pos.X, start.Pos.Y, target.X, target.Y)
+}
+
+// step проигрывает команду марсоходом на плато, false если марсоход не может её выполнить
+func step(p *plateau.Plateau, from State, command byte) (State, bool) {
+	r := rover.Rover{Pos: from.Pos, Direction: from.Direction, Plateau: p}
+	switch command {
+	case 'F':
+		if r.Move(1) != nil {
+			return State{}, false
+		}
+	case 'B':
+		if r.Move(-1) != nil {
+			return State{}, false
+		}
+	case 'L':
+		r.Rotate(1)
+	case 'R':
+		r.Rotate(-1)
+	}
+	return State{Pos: r.Pos, Direction: r.Direction}, true
+}
+
+// path восстанавливает команды от начального состояния до состояния end
+func path(parent []int, command []byte, end int) string {
+	var reversed []byte
+	for i := end; parent[i] != i; i = parent[i] {
+		reversed = append(reversed, command[i])
+	}
+	var b strings.Builder
+	for i := len(reversed) - 1; i >= 0; i-- {
+		b.WriteByte(reversed[i])
+	}
+	return b.String()
+}
+
+// grid прямоугольная область поиска, состояния в ней нумеруются подряд
+type grid struct {
+	minX, minY    int
+	width, height int
+}
+
+func newGrid(p *plateau.Plateau, start, target models.Coordinates) (*grid, error) {
+	minX, maxX := axis(p.Width, start.X, target.X, p.Obstacles, func(c models.Coordinates) int { return c.X })
+	minY, maxY := axis(p.Height, start.Y, target.Y, p.Obstacles, func(c models.Coordinates) int { return c.Y })
+	g := &grid{minX: minX, minY: minY, width: maxX - minX + 1, height: maxY - minY + 1}
+	if g.width <= 0 || g.height <= 0 || g.width > maxCells || g.height > maxCells || g.width*g.height > maxCells {
+		return nil, fmt.Errorf("%w: %dx%d cells", ErrSearchLimit, g.width, g.height)
+	}
+	return g, nil
+}
+
+// axis возвращает границы области поиска по оси: всё плато, если ось ограничена,
+// иначе клетки старта, цели и препятствий с запасом в одну клетку
+func axis(size, start, target int, obstacles plateau.Obstacles, coord func(models.Coordinates) int) (int, int) {
+	if size > 0 {
+		return 0, size - 1
+	}
+	lo, hi := min(start, target), max(start, target)
+	for obstacle := range obstacles {
+		lo, hi = min(lo, coord(obstacle)), max(hi, coord(obstacle))
+	}
+	return lo - 1, hi + 1
+}
+
+func (g *grid) size() int {
+	return g.width * g.height * len(directions)
+}
+
+// index номер состояния, false если состояние вне области поиска
+func (g *grid) index(s State) (int, bool) {
+	x, y := s.Pos.X-g.minX, s.Pos.Y-g.minY
+	if x < 0 || x >= g.width || y < 0 || y >= g.height {
+		return 0, false
+	}
+	for d, dir := range directions {
+		if dir == s.Direction {
+			return (y*g.width+x)*len(directions) + d, true
+		}
+	}
+	return 0, false
+}
+
+func (g *grid) state(i int) State {
+	d := i % len(directions)
+	cell := i / len(directions)
+	return State{
+		Pos:       models.Coordinates{X: g.minX + cell%g.width, Y: g.minY + cell/g.width},
+		Direction: directions[d],
+	}
+}
@@ -0,0 +1,136 @@
+package planner
+
+import (
+	"mars-rover/internal/models"
+	"mars-rover/internal/plateau"
+	"mars-rover/internal/rover"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPlan(t *testing.T) {
+	tests := []struct {
+		name     string
+		plateau  *plateau.Plateau
+		start    State
+		target   models.Coordinates
+		heading  models.Direction
+		expected string
+	}{
+		{
+			name:     "Already at target",
+			start:    State{Pos: models.Coordinates{X: 1, Y: 1}, Direction: models.North},
+			target:   models.Coordinates{X: 1, Y: 1},
+			expected: "",
+		},
+		{
+			name:     "Straight ahead",
+			start:    State{Pos: models.Coordinates{X: 1, Y: 1}, Direction: models.North},
+			target:   models.Coordinates{X: 1, Y: 4},
+			expected: "FFF",
+		},
+		{
+			name:     "Backward is cheaper than turning around",
+			start:    State{Pos: models.Coordinates{X: 1, Y: 1}, Direction: models.North},
+			target:   models.Coordinates{X: 1, Y: -1},
+			expected: "BB",
+		},
+		{
+			name:     "Target heading",
+			start:    State{Pos: models.Coordinates{X: 1, Y: 1}, Direction: models.North},
+			target:   models.Coordinates{X: 1, Y: 2},
+			heading:  models.East,
+			expected: "FR",
+		},
+		{
+			name:     "Around an obstacle",
+			plateau:  &plateau.Plateau{Obstacles: plateau.NewObstacles(models.Coordinates{X: 1, Y: 2})},
+			start:    State{Pos: models.Coordinates{X: 1, Y: 1}, Direction: models.North},
+			target:   models.Coordinates{X: 1, Y: 3},
+			expected: "LFLBBLF",
+		},
+		{
+			name:     "Through the edge of a torus",
+			plateau:  &plateau.Plateau{Width: 5, Height: 5, Edge: plateau.EdgeWrap},
+			start:    State{Pos: models.Coordinates{X: 2, Y: 0}, Direction: models.North},
+			target:   models.Coordinates{X: 2, Y: 4},
+			expected: "B",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			commands, err := Plan(tt.plateau, tt.start, tt.target, tt.heading)
+			require.NoError(t, err)
+			assert.Len(t, commands, len(tt.expected))
+
+			// маршрут может отличаться порядком равноценных команд, поэтому он проверяется проигрыванием
+			r := rover.NewRover(rover.WithPosition(tt.start.Pos), rover.WithDirection(tt.start.Direction))
+			if tt.plateau != nil {
+				r.SetPlateau(tt.plateau)
+			}
+			for _, c := range commands {
+				switch c {
+				case 'F':
+					require.NoError(t, r.Move(1))
+				case 'B':
+					require.NoError(t, r.Move(-1))
+				case 'L':
+					r.Rotate(1)
+				case 'R':
+					r.Rotate(-1)
+				}
+			}
+			assert.Equal(t, tt.target, r.Pos)
+			if tt.heading != "" {
+				assert.Equal(t, tt.heading, r.Direction)
+			}
+		})
+	}
+}
+
+func TestPlanErrors(t *testing.T) {
+	walled := plateau.NewObstacles(
+		models.Coordinates{X: 1, Y: 0}, models.Coordinates{X: 0, Y: 1},
+		models.Coordinates{X: 2, Y: 1}, models.Coordinates{X: 1, Y: 2},
+	)
+	tests := []struct {
+		name     string
+		plateau  *plateau.Plateau
+		target   models.Coordinates
+		expected error
+	}{
+		{
+			name:     "Target outside plateau",
+			plateau:  &plateau.Plateau{Width: 3, Height: 3},
+			target:   models.Coordinates{X: 5, Y: 1},
+			expected: ErrUnreachable,
+		},
+		{
+			name:     "Target blocked",
+			plateau:  &plateau.Plateau{Obstacles: plateau.NewObstacles(models.Coordinates{X: 3, Y: 3})},
+			target:   models.Coordinates{X: 3, Y: 3},
+			expected: ErrUnreachable,
+		},
+		{
+			name:     "Rover is walled in",
+			plateau:  &plateau.Plateau{Obstacles: walled},
+			target:   models.Coordinates{X: 5, Y: 5},
+			expected: ErrUnreachable,
+		},
+		{
+			name:     "Search area is too large",
+			target:   models.Coordinates{X: 1_000_000, Y: 1_000_000},
+			expected: ErrSearchLimit,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Plan(tt.plateau, State{Pos: models.Coordinates{X: 1, Y: 1}, Direction: models.North}, tt.target, "")
+			assert.ErrorIs(t, err, tt.expected)
+		})
+	}
+}
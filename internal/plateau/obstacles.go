@@ -0,0 +1,67 @@
+package plateau
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"mars-rover/internal/models"
+	"os"
+	"strconv"
+	"strings"
+)
+
+var ErrInvalidObstacles = errors.New("obstacles error: invalid obstacle definition")
+
+// Obstacles множество клеток, занятых препятствиями (камни, кратеры)
+type Obstacles map[models.Coordinates]struct{}
+
+func NewObstacles(cells ...models.Coordinates) Obstacles {
+	obstacles := make(Obstacles, len(cells))
+	for _, cell := range cells {
+		obstacles[cell] = struct{}{}
+	}
+	return obstacles
+}
+
+// LoadObstacles загружает препятствия из файла
+func LoadObstacles(filePath string) (Obstacles, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return ParseObstacles(file)
+}
+
+// ParseObstacles читает препятствия построчно в формате "x y".
+// Пустые строки и строки, начинающиеся с #, пропускаются
+func ParseObstacles(r io.Reader) (Obstacles, error) {
+	obstacles := make(Obstacles)
+	scanner := bufio.NewScanner(r)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		fields := strings.Fields(text)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("%w: line %d: expected \"x y\", got %q", ErrInvalidObstacles, line, text)
+		}
+		x, errX := strconv.Atoi(fields[0])
+		y, errY := strconv.Atoi(fields[1])
+		if errX != nil || errY != nil {
+			return nil, fmt.Errorf("%w: line %d: coordinates must be integers, got %q", ErrInvalidObstacles, line, text)
+		}
+		obstacles[models.Coordinates{X: x, Y: y}] = struct{}{}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return obstacles, nil
+}
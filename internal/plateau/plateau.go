@@ -0,0 +1,159 @@
+package plateau
+
+import (
+	"errors"
+	"fmt"
+	"mars-rover/internal/models"
+)
+
+var ErrInvalidPlateau = errors.New("plateau error: invalid configuration")
+
+// EdgeMode поведение марсохода на краю плато
+type EdgeMode string
+
+const (
+	// EdgeReject шаг за край плато отклоняется: марсоход остаётся в последней клетке плато на пути, маршрут прерывается
+	EdgeReject EdgeMode = "reject"
+	// EdgeClamp марсоход доезжает до края плато и продолжает маршрут
+	EdgeClamp EdgeMode = "clamp"
+	// EdgeWrap плато замкнуто в тор: уехав за восточный край, марсоход появляется у западного
+	EdgeWrap EdgeMode = "wrap"
+)
+
+// Plateau прямоугольное плато, клетки которого имеют координаты от (0, 0) до (Width-1, Height-1).
+// Нулевая ширина или высота означает, что по этой оси плато не ограничено
+type Plateau struct {
+	Width     int
+	Height    int
+	Edge      EdgeMode
+	Obstacles Obstacles
+}
+
+func NewPlateau(width, height int, edge EdgeMode) (*Plateau, error) {
+	if width < 0 || height < 0 {
+		return nil, fmt.Errorf("%w: size %dx%d", ErrInvalidPlateau, width, height)
+	}
+	if _, err := ParseEdgeMode(string(edge)); err != nil {
+		return nil, err
+	}
+
+	return &Plateau{
+		Width:  width,
+		Height: height,
+		Edge:   edge,
+	}, nil
+}
+
+func ParseEdgeMode(mode string) (EdgeMode, error) {
+	switch edge := EdgeMode(mode); edge {
+	case EdgeReject, EdgeClamp, EdgeWrap:
+		return edge, nil
+	default:
+		return "", fmt.Errorf("%w: unknown edge mode %q", ErrInvalidPlateau, mode)
+	}
+}
+
+// Contains проверяет, лежит ли клетка в пределах плато
+func (p *Plateau) Contains(c models.Coordinates) bool {
+	return inRange(c.X, p.Width) && inRange(c.Y, p.Height)
+}
+
+// Blocked проверяет, занята ли клетка препятствием
+func (p *Plateau) Blocked(c models.Coordinates) bool {
+	_, ok := p.Obstacles[c]
+	return ok
+}
+
+// Move перемещает марсоход из клетки from на steps клеток вдоль вектора (dx, dy).
+// Возвращает клетку, в которой марсоход оказался, и ErrOutOfBounds или ErrObstacle, если маршрут нужно прервать.
+// Препятствия проверяются на каждой пересечённой клетке, а не только в конечной точке
+func (p *Plateau) Move(from models.Coordinates, dx, dy, steps int) (models.Coordinates, error) {
+	if steps < 0 {
+		dx, dy, steps = -dx, -dy, -steps
+	}
+
+	if distance, ok := p.obstacleAhead(from, dx, dy, steps); ok {
+		obstacle := p.shift(from, dx, dy, distance)
+		return p.shift(from, dx, dy, distance-1),
+			fmt.Errorf("%w: (%d, %d)", models.ErrObstacle, obstacle.X, obstacle.Y)
+	}
+
+	target := p.shift(from, dx, dy, steps)
+	if p.Edge == EdgeWrap || p.Contains(target) {
+		return target, nil
+	}
+
+	// клетки пути до края пройдены, поэтому многошаговый ход заканчивается на краю,
+	// как если бы команды выполнялись по одной
+	if p.Edge == EdgeClamp {
+		return p.clamp(target), nil
+	}
+	return p.clamp(target), fmt.Errorf("%w: (%d, %d)", models.ErrOutOfBounds, target.X, target.Y)
+}
+
+// obstacleAhead ищет ближайшее препятствие на пути длиной steps клеток и возвращает расстояние до него.
+// Перебираются препятствия, а не клетки пути, поэтому проверка не зависит от количества шагов
+func (p *Plateau) obstacleAhead(from models.Coordinates, dx, dy, steps int) (int, bool) {
+	nearest := 0
+	for obstacle := range p.Obstacles {
+		if !p.Contains(obstacle) {
+			continue
+		}
+
+		var distance, size int
+		switch {
+		case dx != 0 && obstacle.Y == from.Y:
+			distance, size = (obstacle.X-from.X)*dx, p.Width
+		case dy != 0 && obstacle.X == from.X:
+			distance, size = (obstacle.Y-from.Y)*dy, p.Height
+		default:
+			continue
+		}
+		if p.Edge == EdgeWrap && size > 0 {
+			distance = (distance%size + size) % size
+		}
+
+		if distance > 0 && distance <= steps && (nearest == 0 || distance < nearest) {
+			nearest = distance
+		}
+	}
+	return nearest, nearest > 0
+}
+
+// shift сдвигает клетку на steps клеток вдоль вектора (dx, dy) без проверки границ
+func (p *Plateau) shift(from models.Coordinates, dx, dy, steps int) models.Coordinates {
+	if p.Edge == EdgeWrap {
+		return models.Coordinates{X: wrap(from.X, dx, steps, p.Width), Y: wrap(from.Y, dy, steps, p.Height)}
+	}
+	return models.Coordinates{X: from.X + dx*steps, Y: from.Y + dy*steps}
+}
+
+func (p *Plateau) clamp(c models.Coordinates) models.Coordinates {
+	return models.Coordinates{X: clamp(c.X, p.Width), Y: clamp(c.Y, p.Height)}
+}
+
+func inRange(v, size int) bool {
+	return size <= 0 || v >= 0 && v < size
+}
+
+func clamp(v, size int) int {
+	if size <= 0 {
+		return v
+	}
+	if v < 0 {
+		return 0
+	}
+	if v >= size {
+		return size - 1
+	}
+	return v
+}
+
+// wrap сдвигает координату v на d*steps по модулю size, не переполняясь при огромном количестве шагов
+func wrap(v, d, steps, size int) int {
+	if size <= 0 {
+		return v + d*steps
+	}
+	shift := d * (steps % size)
+	return ((v%size+shift)%size + size) % size
+}
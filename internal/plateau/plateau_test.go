@@ -0,0 +1,175 @@
+package plateau
+
+import (
+	"mars-rover/internal/models"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewPlateau(t *testing.T) {
+	tests := []struct {
+		name        string
+		width       int
+		height      int
+		edge        EdgeMode
+		expectedErr error
+	}{
+		{"Valid plateau", 5, 5, EdgeReject, nil},
+		{"Unbounded plateau", 0, 0, EdgeClamp, nil},
+		{"Negative width", -1, 5, EdgeReject, ErrInvalidPlateau},
+		{"Unknown edge mode", 5, 5, EdgeMode("bounce"), ErrInvalidPlateau},
+		// режим stop совпадал с reject и удалён
+		{"Removed stop mode", 5, 5, EdgeMode("stop"), ErrInvalidPlateau},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := NewPlateau(tt.width, tt.height, tt.edge)
+			if tt.expectedErr != nil {
+				assert.ErrorIs(t, err, tt.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, &Plateau{Width: tt.width, Height: tt.height, Edge: tt.edge}, p)
+		})
+	}
+}
+
+func TestPlateau_Contains(t *testing.T) {
+	tests := []struct {
+		name     string
+		plateau  Plateau
+		cell     models.Coordinates
+		expected bool
+	}{
+		{"Origin", Plateau{Width: 5, Height: 5}, models.Coordinates{X: 0, Y: 0}, true},
+		{"Upper right corner", Plateau{Width: 5, Height: 5}, models.Coordinates{X: 4, Y: 4}, true},
+		{"Beyond east edge", Plateau{Width: 5, Height: 5}, models.Coordinates{X: 5, Y: 0}, false},
+		{"Beyond south edge", Plateau{Width: 5, Height: 5}, models.Coordinates{X: 0, Y: -1}, false},
+		{"Unbounded axis", Plateau{Width: 5}, models.Coordinates{X: 2, Y: -100}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, tt.plateau.Contains(tt.cell))
+		})
+	}
+}
+
+func TestPlateau_Move(t *testing.T) {
+	tests := []struct {
+		name        string
+		edge        EdgeMode
+		from        models.Coordinates
+		dx, dy      int
+		steps       int
+		expected    models.Coordinates
+		expectedErr error
+	}{
+		{"Inside plateau", EdgeReject, models.Coordinates{X: 1, Y: 1}, 0, 1, 2, models.Coordinates{X: 1, Y: 3}, nil},
+		{"Reject keeps position at edge", EdgeReject, models.Coordinates{X: 1, Y: 4}, 0, 1, 1, models.Coordinates{X: 1, Y: 4}, models.ErrOutOfBounds},
+		{"Reject multi-step stops at edge", EdgeReject, models.Coordinates{X: 1, Y: 1}, 0, 1, 5, models.Coordinates{X: 1, Y: 4}, models.ErrOutOfBounds},
+		{"Reject backwards stops at south edge", EdgeReject, models.Coordinates{X: 1, Y: 2}, 0, 1, -4, models.Coordinates{X: 1, Y: 0}, models.ErrOutOfBounds},
+		{"Clamp to north edge", EdgeClamp, models.Coordinates{X: 1, Y: 1}, 0, 1, 5, models.Coordinates{X: 1, Y: 4}, nil},
+		{"Clamp backwards to west edge", EdgeClamp, models.Coordinates{X: 1, Y: 1}, 1, 0, -3, models.Coordinates{X: 0, Y: 1}, nil},
+		{"Reject at east edge", EdgeReject, models.Coordinates{X: 1, Y: 1}, 1, 0, 10, models.Coordinates{X: 4, Y: 1}, models.ErrOutOfBounds},
+		{"Wrap over east edge", EdgeWrap, models.Coordinates{X: 3, Y: 1}, 1, 0, 3, models.Coordinates{X: 1, Y: 1}, nil},
+		{"Wrap backwards over south edge", EdgeWrap, models.Coordinates{X: 1, Y: 1}, 0, 1, -2, models.Coordinates{X: 1, Y: 4}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &Plateau{Width: 5, Height: 5, Edge: tt.edge}
+			pos, err := p.Move(tt.from, tt.dx, tt.dy, tt.steps)
+			if tt.expectedErr != nil {
+				assert.ErrorIs(t, err, tt.expectedErr)
+			} else {
+				require.NoError(t, err)
+			}
+			assert.Equal(t, tt.expected, pos)
+		})
+	}
+}
+
+func TestPlateau_MoveWithObstacles(t *testing.T) {
+	obstacles := NewObstacles(
+		models.Coordinates{X: 1, Y: 4},
+		models.Coordinates{X: 3, Y: 1},
+	)
+
+	tests := []struct {
+		name        string
+		plateau     Plateau
+		from        models.Coordinates
+		dx, dy      int
+		steps       int
+		expected    models.Coordinates
+		expectedErr error
+	}{
+		{"Collapsed move crosses obstacle", Plateau{Obstacles: obstacles}, models.Coordinates{X: 1, Y: 1}, 0, 1, 4, models.Coordinates{X: 1, Y: 3}, models.ErrObstacle},
+		{"Stops right before obstacle", Plateau{Obstacles: obstacles}, models.Coordinates{X: 1, Y: 1}, 1, 0, 10, models.Coordinates{X: 2, Y: 1}, models.ErrObstacle},
+		{"Backward move away from obstacle", Plateau{Obstacles: obstacles}, models.Coordinates{X: 4, Y: 1}, -1, 0, -2, models.Coordinates{X: 6, Y: 1}, nil},
+		{"Backward move hits obstacle", Plateau{Obstacles: obstacles}, models.Coordinates{X: 5, Y: 1}, 1, 0, -3, models.Coordinates{X: 4, Y: 1}, models.ErrObstacle},
+		{"Obstacle behind is ignored", Plateau{Obstacles: obstacles}, models.Coordinates{X: 1, Y: 5}, 0, 1, 3, models.Coordinates{X: 1, Y: 8}, nil},
+		{"Obstacle before edge", Plateau{Width: 5, Height: 5, Edge: EdgeReject, Obstacles: obstacles}, models.Coordinates{X: 1, Y: 1}, 0, 1, 10, models.Coordinates{X: 1, Y: 3}, models.ErrObstacle},
+		{"Obstacle after wrap", Plateau{Width: 5, Height: 5, Edge: EdgeWrap, Obstacles: obstacles}, models.Coordinates{X: 4, Y: 1}, 1, 0, 6, models.Coordinates{X: 2, Y: 1}, models.ErrObstacle},
+		{"Huge wrap move hits obstacle", Plateau{Width: 5, Height: 5, Edge: EdgeWrap, Obstacles: obstacles}, models.Coordinates{X: 1, Y: 0}, 0, 1, 1_000_000_000_000, models.Coordinates{X: 1, Y: 3}, models.ErrObstacle},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pos, err := tt.plateau.Move(tt.from, tt.dx, tt.dy, tt.steps)
+			if tt.expectedErr != nil {
+				assert.ErrorIs(t, err, tt.expectedErr)
+			} else {
+				require.NoError(t, err)
+			}
+			assert.Equal(t, tt.expected, pos)
+		})
+	}
+}
+
+func TestParseObstacles(t *testing.T) {
+	tests := []struct {
+		name        string
+		input       string
+		expected    Obstacles
+		expectedErr error
+	}{
+		{
+			name:     "Obstacles with comments and blank lines",
+			input:    "# камни\n1 2\n\n  -3 4  \n",
+			expected: NewObstacles(models.Coordinates{X: 1, Y: 2}, models.Coordinates{X: -3, Y: 4}),
+		},
+		{
+			name:     "Empty file",
+			input:    "",
+			expected: Obstacles{},
+		},
+		{
+			name:        "Missing coordinate",
+			input:       "1 2\n3\n",
+			expectedErr: ErrInvalidObstacles,
+		},
+		{
+			name:        "Not a number",
+			input:       "1 y\n",
+			expectedErr: ErrInvalidObstacles,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			obstacles, err := ParseObstacles(strings.NewReader(tt.input))
+			if tt.expectedErr != nil {
+				assert.ErrorIs(t, err, tt.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, obstacles)
+		})
+	}
+}
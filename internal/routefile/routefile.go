@@ -0,0 +1,318 @@
+package routefile
+
+import (
+	"errors"
+	"fmt"
+	"mars-rover/internal/models"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// maxExpandedSize ограничение на размер маршрута после подстановки макросов
+const maxExpandedSize = 1 << 24
+
+var (
+	ErrInvalidHeader  = errors.New("route file error: invalid header")
+	ErrInvalidMacro   = errors.New("route file error: invalid macro")
+	ErrUndefinedMacro = errors.New("route file error: undefined macro")
+	ErrIncludeCycle   = errors.New("route file error: include cycle")
+)
+
+// keywords слова, которые начинают строки-директивы и не могут быть именами макросов
+var keywords = map[string]struct{}{"start": {}, "def": {}, "include": {}}
+
+// File разобранный файл маршрута. Файл может начинаться с заголовка, задающего начальное состояние марсохода,
+// определять макросы и подключать другие файлы:
+//
+//	# патруль вокруг лагеря
+//	start 3 4 E
+//	include patrols.route
+//	def back = 2B 2R   # выехать из кратера
+//	FFLBFRLBBFFRRBBLFR
+//	2(
+//	  square
+//	  back
+//	)
+//
+// Всё от # до конца строки считается комментарием, пробелы и пустые строки пропускаются, строки маршрута
+// склеиваются в одну строку команд, поэтому группа может занимать несколько строк. Пробелы и переводы строк
+// разделяют команды: количество повторов пишется вплотную к команде, 10F, но не 1 0F. Имя макроса состоит
+// из строчных латинских букв и подчёркиваний, при вызове макрос подставляется как группа: 2square означает
+// 2(тело square)
+type File struct {
+	// Start начальное состояние из заголовка, nil если заголовка нет
+	Start *Start
+	// Commands команды маршрута с подставленными макросами и подключёнными файлами
+	Commands string
+
+	source *source
+}
+
+// Locate возвращает место в файле маршрута команды со смещением offset в Commands
+func (f *File) Locate(offset int) Location {
+	if f.source == nil {
+		return Location{}
+	}
+	return f.source.locate(offset)
+}
+
+// Start начальное состояние марсохода
+type Start struct {
+	Pos       models.Coordinates
+	Direction models.Direction
+}
+
+// Load загружает файл маршрута. Пути в include считаются относительно каталога файла, который их подключает
+func Load(filePath string) (*File, error) {
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	abs, err := filepath.Abs(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	l := &loader{macros: map[string]*source{}, defined: map[string]Location{}, stack: []string{abs},
+		lines: map[string][]string{}}
+	return l.parse(string(content), filePath, filepath.Dir(filePath))
+}
+
+// Parse разбирает содержимое файла маршрута. Пути в include считаются относительно рабочего каталога
+func Parse(content string) (*File, error) {
+	l := &loader{macros: map[string]*source{}, defined: map[string]Location{}, lines: map[string][]string{}}
+	return l.parse(content, "", ".")
+}
+
+// loader собирает маршрут из основного файла и подключённых файлов с общей таблицей макросов
+type loader struct {
+	macros map[string]*source
+	// defined места определений макросов с абсолютными путями файлов
+	defined map[string]Location
+	// stack абсолютные пути файлов, которые подключаются в данный момент, для поиска циклов
+	stack []string
+	// lines строки прочитанных файлов для фрагментов в ошибках
+	lines map[string][]string
+	route source
+}
+
+// parse разбирает основной файл и проверяет синтаксис собранного маршрута, ошибка указывает на место в файле
+func (l *loader) parse(content, name, dir string) (*File, error) {
+	file := &File{}
+	if err := l.read(content, name, dir, file); err != nil {
+		return nil, err
+	}
+	if err := l.route.validate(l.lines); err != nil {
+		return nil, err
+	}
+	file.Commands, file.source = l.route.String(), &l.route
+	return file, nil
+}
+
+// read разбирает строки файла. file передаётся только для основного файла: заголовок допустим лишь в нём
+func (l *loader) read(content, name, dir string, file *File) error {
+	l.lines[name] = strings.Split(content, "\n")
+	first := true
+	for i, line := range l.lines[name] {
+		line, _, _ = strings.Cut(line, "#")
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" {
+			continue
+		}
+		at := column(Location{File: name, Line: i + 1}, line, strings.Index(line, trimmed))
+		header := first
+		first = false
+
+		keyword := strings.Fields(trimmed)[0]
+		args := strings.Index(line, keyword) + len(keyword)
+		switch keyword {
+		case "start":
+			if file == nil || !header {
+				return &SourceError{Location: at,
+					Err: fmt.Errorf("%w: start header must be the first line of the main route file", ErrInvalidHeader)}
+			}
+			start, err := parseStart(trimmed)
+			if err != nil {
+				return &SourceError{Location: at, Err: err}
+			}
+			file.Start = start
+		case "def":
+			if err := l.define(line, args, at); err != nil {
+				return err
+			}
+		case "include":
+			if err := l.include(strings.TrimSpace(line[args:]), dir); err != nil {
+				// ошибка внутри подключённого файла уже указывает на место в нём
+				var (
+					sourceErr  *SourceError
+					symbolErrs models.SymbolErrors
+				)
+				if !errors.As(err, &sourceErr) && !errors.As(err, &symbolErrs) {
+					err = &SourceError{Location: at, Err: err}
+				}
+				return err
+			}
+		default:
+			if err := l.expand(&l.route, line, 0, at); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// define разбирает определение макроса "name = body", начинающееся с байта from строки line.
+// Тело может вызывать только уже определённые макросы, поэтому рекурсивные макросы невозможны
+func (l *loader) define(line string, from int, at Location) error {
+	definition := line[from:]
+	name, _, ok := strings.Cut(definition, "=")
+	name = strings.TrimSpace(name)
+	if !ok || !isName(name) {
+		return &SourceError{Location: at, Err: fmt.Errorf("%w: expected \"def name = commands\" with a lowercase name, got %q",
+			ErrInvalidMacro, strings.TrimSpace(definition))}
+	}
+	if _, reserved := keywords[name]; reserved {
+		return &SourceError{Location: at, Err: fmt.Errorf("%w: %q is a reserved word", ErrInvalidMacro, name)}
+	}
+	site := at
+	if abs, err := filepath.Abs(at.File); err == nil && at.File != "" {
+		site.File = abs
+	}
+	if previous, defined := l.defined[name]; defined {
+		// файл, подключённый из нескольких файлов, определяет свои макросы повторно, это то же определение
+		if previous == site {
+			return nil
+		}
+		return &SourceError{Location: at, Err: fmt.Errorf("%w: %q is already defined", ErrInvalidMacro, name)}
+	}
+
+	body := &source{}
+	if err := l.expand(body, line, from+strings.Index(definition, "=")+1, at); err != nil {
+		return err
+	}
+	if err := body.validate(l.lines); err != nil {
+		return err
+	}
+	l.macros[name], l.defined[name] = body, site
+	return nil
+}
+
+// include подключает файл: его макросы становятся доступны, а строки маршрута вставляются на место директивы
+func (l *loader) include(path, dir string) error {
+	if path == "" {
+		return fmt.Errorf("%w: include needs a file path", ErrInvalidMacro)
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(dir, path)
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+	for i, included := range l.stack {
+		if included == abs {
+			chain := append(append([]string{}, l.stack[i:]...), abs)
+			return fmt.Errorf("%w: %s", ErrIncludeCycle, strings.Join(chain, " -> "))
+		}
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	l.stack = append(l.stack, abs)
+	defer func() { l.stack = l.stack[:len(l.stack)-1] }()
+	return l.read(string(content), path, filepath.Dir(path), nil)
+}
+
+// expand переносит команды из строки line, начиная с байта from, в dst: пробелы пропускаются, но разделяют
+// команды, вызовы макросов подставляются как группы
+func (l *loader) expand(dst *source, line string, from int, at Location) error {
+	for i := from; i < len(line); {
+		j := i + 1
+		switch c := line[i]; {
+		case isSpace(c):
+		case isNameByte(c):
+			for j < len(line) && isNameByte(line[j]) {
+				j++
+			}
+			body, ok := l.macros[line[i:j]]
+			if !ok {
+				return &SourceError{Location: column(at, line, i), Err: fmt.Errorf("%w: %q", ErrUndefinedMacro, line[i:j])}
+			}
+			dst.write("(", column(at, line, i))
+			dst.append(body)
+			dst.write(")", column(at, line, j-1))
+		case c >= utf8.RuneSelf:
+			_, size := utf8.DecodeRuneInString(line[i:])
+			j = i + size
+			dst.write(line[i:j], column(at, line, i))
+		default:
+			for j < len(line) && !isSpace(line[j]) && !isNameByte(line[j]) && line[j] < utf8.RuneSelf {
+				j++
+			}
+			// пробел и конец строки разделяют команды, поэтому количество повторов должно стоять вплотную
+			// к команде, группе или макросу: "1 0F" не склеивается в 10F
+			if isDigit(line[j-1]) && (j == len(line) || isSpace(line[j])) {
+				k := j - 1
+				for k > i && isDigit(line[k-1]) {
+					k--
+				}
+				return &SourceError{Location: column(at, line, k),
+					Err: fmt.Errorf("%w: count must be followed by a command or a group", models.ErrRouteSyntax)}
+			}
+			dst.write(line[i:j], column(at, line, i))
+		}
+		if dst.text.Len() > maxExpandedSize {
+			return &SourceError{Location: column(at, line, i),
+				Err: fmt.Errorf("%w: route is too large after macro expansion", ErrInvalidMacro)}
+		}
+		i = j
+	}
+	return nil
+}
+
+// parseStart разбирает заголовок "start x y направление"
+func parseStart(header string) (*Start, error) {
+	fields := strings.Fields(header)
+	if len(fields) != 4 {
+		return nil, fmt.Errorf("%w: expected \"start x y direction\", got %q", ErrInvalidHeader, header)
+	}
+	x, errX := strconv.Atoi(fields[1])
+	y, errY := strconv.Atoi(fields[2])
+	if errX != nil || errY != nil {
+		return nil, fmt.Errorf("%w: coordinates must be integers, got %q", ErrInvalidHeader, header)
+	}
+	dir, err := models.ParseDirection(fields[3])
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidHeader, err)
+	}
+	return &Start{Pos: models.Coordinates{X: x, Y: y}, Direction: dir}, nil
+}
+
+func isName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i := 0; i < len(name); i++ {
+		if !isNameByte(name[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func isNameByte(c byte) bool {
+	return c >= 'a' && c <= 'z' || c == '_'
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\r'
+}
@@ -0,0 +1,339 @@
+package routefile
+
+import (
+	"errors"
+	"mars-rover/internal/models"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name        string
+		content     string
+		expected    *File
+		expectedErr error
+	}{
+		{
+			name:     "Commands only",
+			content:  "FFLRB\n",
+			expected: &File{Commands: "FFLRB"},
+		},
+		{
+			name:    "Header with start state",
+			content: "start 3 -4 E\nFFLRB\n",
+			expected: &File{
+				Start:    &Start{Pos: models.Coordinates{X: 3, Y: -4}, Direction: models.East},
+				Commands: "FFLRB",
+			},
+		},
+		{
+			name:    "Header without commands",
+			content: "  start 0 0 S  ",
+			expected: &File{
+				Start: &Start{Pos: models.Coordinates{X: 0, Y: 0}, Direction: models.South},
+			},
+		},
+		{
+			name:     "Multi-line commands",
+			content:  "FF\nLR\n\nB\n",
+			expected: &File{Commands: "FFLRB"},
+		},
+		{
+			name:    "Comments and whitespace",
+			content: "# разведка\n\nstart 1 1 N # старт у лагеря\n  FF L\tR # вперёд\n2(\n  F B\n)\r\n",
+			expected: &File{
+				Start:    &Start{Pos: models.Coordinates{X: 1, Y: 1}, Direction: models.North},
+				Commands: "FFLR2(FB)",
+			},
+		},
+		{
+			name:     "Macro calls separated by spaces",
+			content:  "def a = F\ndef b = 2L\na b 3a\n",
+			expected: &File{Commands: "(F)(2L)3(F)"},
+		},
+		{
+			name:    "Header with macros",
+			content: "start 1 1 N\ndef square = 4(FFR)\ndef back_out = 2Bsquare\nF2square\nback_out\n",
+			expected: &File{
+				Start:    &Start{Pos: models.Coordinates{X: 1, Y: 1}, Direction: models.North},
+				Commands: "F2(4(FFR))(2B(4(FFR)))",
+			},
+		},
+		{
+			name:        "Undefined macro",
+			content:     "def square = 4(FFR)\nFsquaree\n",
+			expectedErr: ErrUndefinedMacro,
+		},
+		{
+			name:        "Macro used before definition",
+			content:     "def a = b\ndef b = F\n",
+			expectedErr: ErrUndefinedMacro,
+		},
+		{
+			name:        "Macro redefinition",
+			content:     "def a = F\ndef a = B\n",
+			expectedErr: ErrInvalidMacro,
+		},
+		{
+			name:        "Macro with invalid name",
+			content:     "def Patrol = F\n",
+			expectedErr: ErrInvalidMacro,
+		},
+		{
+			name:        "Macro with reserved name",
+			content:     "def include = F\n",
+			expectedErr: ErrInvalidMacro,
+		},
+		{
+			name:        "Macro with invalid body",
+			content:     "def a = 2(FF\n",
+			expectedErr: models.ErrRouteSyntax,
+		},
+		{
+			name:        "Header after commands",
+			content:     "FF\nstart 1 1 N\n",
+			expectedErr: ErrInvalidHeader,
+		},
+		{
+			name:        "Header with invalid direction",
+			content:     "start 1 1 Q\nF",
+			expectedErr: models.ErrInvalidDirection,
+		},
+		{
+			name:        "Header with invalid coordinates",
+			content:     "start 1 y N\nF",
+			expectedErr: ErrInvalidHeader,
+		},
+		{
+			name:        "Header with missing fields",
+			content:     "start 1 1\nF",
+			expectedErr: ErrInvalidHeader,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file, err := Parse(tt.content)
+			if tt.expectedErr != nil {
+				assert.ErrorIs(t, err, tt.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected.Start, file.Start)
+			assert.Equal(t, tt.expected.Commands, file.Commands)
+		})
+	}
+}
+
+func TestParseErrorLocation(t *testing.T) {
+	tests := []struct {
+		name        string
+		content     string
+		expectedErr error
+		expected    Location
+	}{
+		{
+			name:        "Invalid symbol",
+			content:     "FF\n  F X\n",
+			expectedErr: models.ErrIncorrectSymbol,
+			expected:    Location{Line: 2, Column: 5},
+		},
+		{
+			name:        "Unclosed group spanning lines",
+			content:     "# патруль\n2(\n  FF # вперёд\n  R\n",
+			expectedErr: models.ErrRouteSyntax,
+			expected:    Location{Line: 2, Column: 2},
+		},
+		{
+			name:        "Invalid symbol after cyrillic comment on previous line",
+			content:     "F # вперёд\nЖF\n",
+			expectedErr: models.ErrIncorrectSymbol,
+			expected:    Location{Line: 2, Column: 1},
+		},
+		{
+			name:        "Invalid symbol in macro body",
+			content:     "def a = F Q\n",
+			expectedErr: models.ErrIncorrectSymbol,
+			expected:    Location{Line: 1, Column: 11},
+		},
+		{
+			name:        "Undefined macro",
+			content:     "def a = FF\n F unknown\n",
+			expectedErr: ErrUndefinedMacro,
+			expected:    Location{Line: 2, Column: 4},
+		},
+		{
+			name:        "Error after macro call",
+			content:     "def a = FF\na)\n",
+			expectedErr: models.ErrRouteSyntax,
+			expected:    Location{Line: 2, Column: 2},
+		},
+		{
+			name:        "Count separated by space",
+			content:     "FF 1 0F\n",
+			expectedErr: models.ErrRouteSyntax,
+			expected:    Location{Line: 1, Column: 4},
+		},
+		{
+			name:        "Count at the end of line",
+			content:     "F\n  L12\n0F\n",
+			expectedErr: models.ErrRouteSyntax,
+			expected:    Location{Line: 2, Column: 4},
+		},
+		{
+			name:        "Count separated from macro call",
+			content:     "def a = FF\n2 a\n",
+			expectedErr: models.ErrRouteSyntax,
+			expected:    Location{Line: 2, Column: 1},
+		},
+		{
+			name:        "Count separated from group in macro body",
+			content:     "def a = 3 (FR)\n",
+			expectedErr: models.ErrRouteSyntax,
+			expected:    Location{Line: 1, Column: 9},
+		},
+		{
+			name:        "Header after commands",
+			content:     "F\n  start 1 1 N\n",
+			expectedErr: ErrInvalidHeader,
+			expected:    Location{Line: 2, Column: 3},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse(tt.content)
+			require.Error(t, err)
+			assert.ErrorIs(t, err, tt.expectedErr)
+
+			var symbolErrs models.SymbolErrors
+			if errors.As(err, &symbolErrs) {
+				assert.Equal(t, tt.expected.String(), symbolErrs[0].Location)
+				return
+			}
+			var sourceErr *SourceError
+			require.ErrorAs(t, err, &sourceErr)
+			assert.Equal(t, tt.expected, sourceErr.Location)
+		})
+	}
+}
+
+func TestParseInvalidSymbols(t *testing.T) {
+	_, err := Parse("def a = FF\nFF a\n\tFX # вперёд Z\nЖ")
+
+	var symbolErrs models.SymbolErrors
+	require.ErrorAs(t, err, &symbolErrs)
+	assert.Equal(t, models.SymbolErrors{
+		{Offset: 7, Symbol: 'X', Location: "3:3", Context: " FX # вперёд Z", Caret: 2},
+		{Offset: 8, Symbol: 'Ж', Location: "4:1", Context: "Ж", Caret: 0},
+	}, symbolErrs)
+}
+
+func TestLoadWithInclude(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "lib"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "lib", "patrols.route"),
+		[]byte("include moves.route\ndef square = 4(forwardR)\n"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "lib", "moves.route"),
+		[]byte("def forward = FF\nL\n"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "main.route"),
+		[]byte("start 0 0 E\ninclude lib/patrols.route\n2square\n"), 0644))
+
+	file, err := Load(filepath.Join(dir, "main.route"))
+	require.NoError(t, err)
+	assert.Equal(t, &Start{Pos: models.Coordinates{X: 0, Y: 0}, Direction: models.East}, file.Start)
+	assert.Equal(t, "L2(4((FF)R))", file.Commands)
+}
+
+func TestLoadWithDiamondInclude(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0644))
+	}
+	write("macros.route", "def sq = 4(FFR)\n")
+	write("a.route", "include macros.route\nsq\n")
+	write("b.route", "include ./macros.route\nL sq\n")
+	write("main.route", "include a.route\ninclude b.route\n")
+	write("clash.route", "include a.route\ndef sq = 4(FFL)\n")
+
+	file, err := Load(filepath.Join(dir, "main.route"))
+	require.NoError(t, err)
+	assert.Equal(t, "(4(FFR))L(4(FFR))", file.Commands)
+
+	// другое определение с тем же именем по-прежнему ошибка
+	_, err = Load(filepath.Join(dir, "clash.route"))
+	assert.ErrorIs(t, err, ErrInvalidMacro)
+}
+
+func TestLoadIncludeErrors(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+		return path
+	}
+
+	tests := []struct {
+		name        string
+		path        string
+		expectedErr error
+	}{
+		{
+			name:        "Self include",
+			path:        write("self.route", "F\ninclude self.route\n"),
+			expectedErr: ErrIncludeCycle,
+		},
+		{
+			name:        "Indirect cycle",
+			path:        write("a.route", "include b.route\n"),
+			expectedErr: ErrIncludeCycle,
+		},
+		{
+			name:        "Missing include",
+			path:        write("missing.route", "include nowhere.route\n"),
+			expectedErr: os.ErrNotExist,
+		},
+		{
+			name:        "Invalid symbol in included file",
+			path:        write("bad.route", "F\ninclude b_bad.route\n"),
+			expectedErr: models.ErrIncorrectSymbol,
+		},
+		{
+			name:        "Header in included file",
+			path:        write("header.route", "include b_header.route\n"),
+			expectedErr: ErrInvalidHeader,
+		},
+	}
+	write("b.route", "include a.route\n")
+	write("b_header.route", "start 1 1 N\n")
+	write("b_bad.route", "# опечатка\nFFX\n")
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Load(tt.path)
+			assert.ErrorIs(t, err, tt.expectedErr)
+		})
+	}
+}
+
+func TestLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "route.txt")
+	require.NoError(t, os.WriteFile(path, []byte("start 2 2 W\nFF\n"), 0644))
+
+	file, err := Load(path)
+	require.NoError(t, err)
+	assert.Equal(t, &Start{Pos: models.Coordinates{X: 2, Y: 2}, Direction: models.West}, file.Start)
+	assert.Equal(t, "FF", file.Commands)
+
+	require.NoError(t, os.WriteFile(path, []byte("FF\nFXF\n"), 0644))
+	_, err = Load(path)
+	assert.EqualError(t, err, "validation error: unexpected input: 'X' at "+path+":2:2")
+
+	_, err = Load(filepath.Join(t.TempDir(), "missing.txt"))
+	assert.Error(t, err)
+}
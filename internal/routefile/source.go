@@ -0,0 +1,121 @@
+package routefile
+
+import (
+	"errors"
+	"fmt"
+	"mars-rover/internal/models"
+	"mars-rover/internal/routelang"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// Location место в файле маршрута, строки и столбцы нумеруются с единицы
+type Location struct {
+	// File путь к файлу, пустой для маршрута, разобранного из строки
+	File   string
+	Line   int
+	Column int
+}
+
+func (l Location) String() string {
+	if l.File == "" {
+		return fmt.Sprintf("%d:%d", l.Line, l.Column)
+	}
+	return fmt.Sprintf("%s:%d:%d", l.File, l.Line, l.Column)
+}
+
+// SourceError ошибка в файле маршрута с местом, на которое она указывает
+type SourceError struct {
+	Location
+	Err error
+}
+
+func (e *SourceError) Error() string {
+	return fmt.Sprintf("%s: %v", e.Location, e.Err)
+}
+
+func (e *SourceError) Unwrap() error {
+	return e.Err
+}
+
+// origin начало отрезка строки команд, символы которого идут подряд в одной строке исходного файла.
+// Смещение внутри отрезка переводится в столбец один к одному, поэтому отрезки состоят из однобайтовых символов
+type origin struct {
+	offset int
+	loc    Location
+}
+
+// source строка команд вместе с картой, по которой смещение в ней переводится в место в исходном файле
+type source struct {
+	text    strings.Builder
+	origins []origin
+}
+
+// write дописывает отрезок команд, первый символ которого находится в исходном файле в месте loc
+func (s *source) write(text string, loc Location) {
+	s.origins = append(s.origins, origin{offset: s.text.Len(), loc: loc})
+	s.text.WriteString(text)
+}
+
+// append дописывает другую строку команд, например тело макроса, сохраняя места её символов
+func (s *source) append(other *source) {
+	for _, o := range other.origins {
+		s.origins = append(s.origins, origin{offset: s.text.Len() + o.offset, loc: o.loc})
+	}
+	s.text.WriteString(other.text.String())
+}
+
+func (s *source) String() string {
+	return s.text.String()
+}
+
+// locate переводит смещение в строке команд в место в исходном файле
+func (s *source) locate(offset int) Location {
+	i := sort.Search(len(s.origins), func(i int) bool { return s.origins[i].offset > offset }) - 1
+	if i < 0 {
+		return Location{}
+	}
+	loc := s.origins[i].loc
+	loc.Column += offset - s.origins[i].offset
+	return loc
+}
+
+// validate разбирает строку команд и переводит смещения ошибок в места в исходном файле.
+// Для недопустимых символов фрагмент вокруг символа берётся из строки файла, lines - строки файлов по их путям
+func (s *source) validate(lines map[string][]string) error {
+	_, err := routelang.Parse(s.String())
+
+	var symbolErrs models.SymbolErrors
+	if errors.As(err, &symbolErrs) {
+		for _, symbolErr := range symbolErrs {
+			loc := s.locate(symbolErr.Offset)
+			line := lines[loc.File][loc.Line-1]
+			context := models.NewSymbolError(line, byteOffset(line, loc.Column))
+			symbolErr.Location, symbolErr.Context, symbolErr.Caret = loc.String(), context.Context, context.Caret
+		}
+		return symbolErrs
+	}
+
+	var syntaxErr *routelang.SyntaxError
+	if !errors.As(err, &syntaxErr) {
+		return err
+	}
+	return &SourceError{Location: s.locate(syntaxErr.Offset), Err: fmt.Errorf("%w: %s", syntaxErr.Err, syntaxErr.Msg)}
+}
+
+// byteOffset возвращает смещение в байтах столбца column строки line
+func byteOffset(line string, column int) int {
+	offset := 0
+	for i := 1; i < column && offset < len(line); i++ {
+		_, size := utf8.DecodeRuneInString(line[offset:])
+		offset += size
+	}
+	return offset
+}
+
+// column возвращает место байта i строки line, столбцы считаются в символах
+func column(loc Location, line string, i int) Location {
+	loc.Column = utf8.RuneCountInString(line[:i]) + 1
+	return loc
+}
@@ -0,0 +1,349 @@
+package routelang
+
+import (
+	"fmt"
+	"mars-rover/internal/models"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// maxExpandedLength ограничение на длину маршрута после раскрытия повторов,
+// чтобы суммарное количество шагов и поворотов гарантированно помещалось в int
+const maxExpandedLength = 1 << 62
+
+// SyntaxError ошибка разбора маршрута с позицией в исходной строке
+type SyntaxError struct {
+	// Offset смещение в байтах от начала маршрута
+	Offset int
+	Msg    string
+	// Err models.ErrRouteSyntax
+	Err error
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("%v: %s at offset %d", e.Err, e.Msg, e.Offset)
+}
+
+func (e *SyntaxError) Unwrap() error {
+	return e.Err
+}
+
+// Node узел синтаксического дерева маршрута
+type Node interface {
+	// Position смещение узла в исходной строке
+	Position() int
+	// Repeat количество повторов узла
+	Repeat() int
+}
+
+// Command команда F, B, L или R, повторённая Count раз: 10F
+type Command struct {
+	// Pos, End начало узла и смещение сразу за ним
+	Pos    int
+	End    int
+	Count  int
+	Symbol rune
+}
+
+func (c *Command) Position() int { return c.Pos }
+func (c *Command) Repeat() int   { return c.Count }
+
+// Group группа команд, повторённая Count раз: 3(FFR)
+type Group struct {
+	// Pos, End начало узла и смещение сразу за закрывающей скобкой
+	Pos   int
+	End   int
+	Count int
+	Body  []Node
+}
+
+func (g *Group) Position() int { return g.Pos }
+func (g *Group) Repeat() int   { return g.Count }
+
+// Program разобранный маршрут
+type Program struct {
+	Nodes []Node
+}
+
+// Parse разбирает маршрут на языке с повторами и группами:
+//
+//	route   = item*
+//	item    = [count] (command | group)
+//	command = "F" | "B" | "L" | "R"
+//	group   = "(" route ")"
+//
+// Например, 10F, 3(FFR) или 2(F3(LF))
+//
+// Если в маршруте есть недопустимые символы, возвращается models.SymbolErrors со всеми такими символами
+func Parse(src string) (*Program, error) {
+	if err := checkSymbols(src); err != nil {
+		return nil, err
+	}
+	p := &parser{src: src}
+	nodes, err := p.parseRoute(-1)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := expandedLength(nodes); err != nil {
+		return nil, err
+	}
+	return &Program{Nodes: nodes}, nil
+}
+
+// Walk раскрывает маршрут лениво, передавая в yield команды с количеством повторов и диапазоном узла
+// в исходной строке. Группы из однотипных команд (только движения или только повороты) сворачиваются в одну команду
+// с диапазоном всей группы, поэтому 1000000000(FFB) не перебирается по итерациям.
+// Обход прекращается, если yield вернул false
+func (p *Program) Walk(yield func(command rune, count int, span models.Span) bool) {
+	walk(p.Nodes, true, yield)
+}
+
+// Steps сколько раз Walk вызовет yield: группы из однотипных команд сворачиваются, смешанные группы
+// раскрываются по итерациям. Считается без перебора повторов, по этой длине оптимизатор оценивает работу до обхода
+func (p *Program) Steps() int {
+	return steps(p.Nodes)
+}
+
+func steps(nodes []Node) int {
+	total := 0
+	for _, node := range nodes {
+		switch n := node.(type) {
+		case *Command:
+			if n.Count > 0 {
+				total++
+			}
+		case *Group:
+			if _, count, ok := fold(n); ok {
+				if count > 0 {
+					total++
+				}
+				continue
+			}
+			total += steps(n.Body) * n.Count
+		}
+	}
+	return total
+}
+
+// Len длина маршрута после раскрытия повторов
+func (p *Program) Len() int {
+	length, _ := expandedLength(p.Nodes)
+	return length
+}
+
+// Count количество команд из symbols в маршруте после раскрытия повторов: Count("FB") - пройденные клетки,
+// Count("LR") - повороты на 90 градусов. Считается без перебора повторов
+func (p *Program) Count(symbols string) int {
+	return count(p.Nodes, symbols)
+}
+
+func count(nodes []Node, symbols string) int {
+	total := 0
+	for _, node := range nodes {
+		switch n := node.(type) {
+		case *Command:
+			if strings.ContainsRune(symbols, n.Symbol) {
+				total += n.Count
+			}
+		case *Group:
+			total += count(n.Body, symbols) * n.Count
+		}
+	}
+	return total
+}
+
+// Each раскрывает маршрут без сворачивания групп: команды передаются в yield в том порядке,
+// в котором их выполнял бы марсоход, поэтому по ним можно восстановить каждую посещённую клетку
+func (p *Program) Each(yield func(command rune, count int, span models.Span) bool) {
+	walk(p.Nodes, false, yield)
+}
+
+func walk(nodes []Node, folding bool, yield func(rune, int, models.Span) bool) bool {
+	for _, node := range nodes {
+		switch n := node.(type) {
+		case *Command:
+			if n.Count > 0 && !yield(n.Symbol, n.Count, models.Span{Start: n.Pos, End: n.End}) {
+				return false
+			}
+		case *Group:
+			if command, count, ok := fold(n); ok && folding {
+				if count > 0 && !yield(command, count, models.Span{Start: n.Pos, End: n.End}) {
+					return false
+				}
+				continue
+			}
+			for i := 0; i < n.Count; i++ {
+				if !walk(n.Body, folding, yield) {
+					return false
+				}
+			}
+		}
+	}
+	return true
+}
+
+// fold сворачивает группу из однотипных команд в одну команду
+func fold(g *Group) (rune, int, bool) {
+	movement, ok := class(g.Body)
+	if !ok {
+		return 0, 0, false
+	}
+
+	net := 0
+	walk(g.Body, true, func(command rune, count int, _ models.Span) bool {
+		switch command {
+		case 'F', 'L':
+			net += count
+		case 'B', 'R':
+			net -= count
+		}
+		return true
+	})
+	if movement {
+		net *= g.Count
+	} else {
+		net = net % 4 * (g.Count % 4) % 4
+	}
+
+	switch {
+	case movement && net >= 0:
+		return 'F', net, true
+	case movement:
+		return 'B', -net, true
+	case net >= 0:
+		return 'L', net, true
+	default:
+		return 'R', -net, true
+	}
+}
+
+// class определяет, состоят ли узлы только из движений или только из поворотов
+func class(nodes []Node) (movement bool, ok bool) {
+	seen := false
+	for _, node := range nodes {
+		var nodeMovement bool
+		switch n := node.(type) {
+		case *Command:
+			nodeMovement = n.Symbol == 'F' || n.Symbol == 'B'
+		case *Group:
+			if nodeMovement, ok = class(n.Body); !ok {
+				return false, false
+			}
+		}
+		if seen && nodeMovement != movement {
+			return false, false
+		}
+		movement, seen = nodeMovement, true
+	}
+	return movement, true
+}
+
+// expandedLength считает длину маршрута после раскрытия повторов и проверяет, что она не слишком велика
+func expandedLength(nodes []Node) (int, error) {
+	total := 0
+	for _, node := range nodes {
+		length := 1
+		if g, ok := node.(*Group); ok {
+			var err error
+			if length, err = expandedLength(g.Body); err != nil {
+				return 0, err
+			}
+		}
+		if node.Repeat() != 0 && length > maxExpandedLength/node.Repeat() {
+			return 0, syntaxError(node.Position(), models.ErrRouteSyntax, "route is too long after expansion")
+		}
+		total += length * node.Repeat()
+		if total > maxExpandedLength {
+			return 0, syntaxError(node.Position(), models.ErrRouteSyntax, "route is too long after expansion")
+		}
+	}
+	return total, nil
+}
+
+// checkSymbols за один проход собирает все символы, которые не могут встретиться в маршруте
+func checkSymbols(src string) error {
+	var errs models.SymbolErrors
+	for offset, r := range src {
+		if !strings.ContainsRune("FBLR()0123456789", r) {
+			errs = append(errs, models.NewSymbolError(src, offset))
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+type parser struct {
+	src string
+	pos int
+}
+
+// parseRoute разбирает последовательность элементов до конца строки или до закрывающей скобки группы,
+// открытой в позиции open (-1 для маршрута верхнего уровня)
+func (p *parser) parseRoute(open int) ([]Node, error) {
+	var nodes []Node
+	for p.pos < len(p.src) {
+		start := p.pos
+		count, hasCount, err := p.parseCount()
+		if err != nil {
+			return nil, err
+		}
+		if p.pos >= len(p.src) {
+			return nil, syntaxError(start, models.ErrRouteSyntax, "count must be followed by a command or a group")
+		}
+
+		r, size := utf8.DecodeRuneInString(p.src[p.pos:])
+		switch r {
+		case 'F', 'B', 'L', 'R':
+			p.pos += size
+			nodes = append(nodes, &Command{Pos: start, End: p.pos, Count: count, Symbol: r})
+		case '(':
+			paren := p.pos
+			p.pos += size
+			body, err := p.parseRoute(paren)
+			if err != nil {
+				return nil, err
+			}
+			nodes = append(nodes, &Group{Pos: start, End: p.pos, Count: count, Body: body})
+		case ')':
+			if hasCount {
+				return nil, syntaxError(start, models.ErrRouteSyntax, "count must be followed by a command or a group")
+			}
+			if open < 0 {
+				return nil, syntaxError(p.pos, models.ErrRouteSyntax, "unexpected ')'")
+			}
+			p.pos += size
+			return nodes, nil
+		default:
+			return nil, syntaxError(p.pos, models.ErrRouteSyntax, "unexpected "+strconv.QuoteRune(r))
+		}
+	}
+
+	if open >= 0 {
+		return nil, syntaxError(open, models.ErrRouteSyntax, "unclosed '('")
+	}
+	return nodes, nil
+}
+
+// parseCount разбирает необязательное количество повторов, по умолчанию 1
+func (p *parser) parseCount() (int, bool, error) {
+	start := p.pos
+	for p.pos < len(p.src) && p.src[p.pos] >= '0' && p.src[p.pos] <= '9' {
+		p.pos++
+	}
+	if start == p.pos {
+		return 1, false, nil
+	}
+
+	count, err := strconv.Atoi(p.src[start:p.pos])
+	if err != nil || count > maxExpandedLength {
+		return 0, false, syntaxError(start, models.ErrRouteSyntax, "count is too large")
+	}
+	return count, true, nil
+}
+
+func syntaxError(offset int, err error, msg string) *SyntaxError {
+	return &SyntaxError{Offset: offset, Msg: msg, Err: err}
+}
@@ -0,0 +1,290 @@
+package routelang
+
+import (
+	"errors"
+	"mars-rover/internal/models"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name     string
+		src      string
+		expected []Node
+	}{
+		{
+			name:     "Empty route",
+			src:      "",
+			expected: nil,
+		},
+		{
+			name: "Plain commands",
+			src:  "FL",
+			expected: []Node{
+				&Command{Pos: 0, End: 1, Count: 1, Symbol: 'F'},
+				&Command{Pos: 1, End: 2, Count: 1, Symbol: 'L'},
+			},
+		},
+		{
+			name: "Repeat count",
+			src:  "10FR",
+			expected: []Node{
+				&Command{Pos: 0, End: 3, Count: 10, Symbol: 'F'},
+				&Command{Pos: 3, End: 4, Count: 1, Symbol: 'R'},
+			},
+		},
+		{
+			name: "Nested groups",
+			src:  "2(F3(LF))",
+			expected: []Node{
+				&Group{Pos: 0, End: 9, Count: 2, Body: []Node{
+					&Command{Pos: 2, End: 3, Count: 1, Symbol: 'F'},
+					&Group{Pos: 3, End: 8, Count: 3, Body: []Node{
+						&Command{Pos: 5, End: 6, Count: 1, Symbol: 'L'},
+						&Command{Pos: 6, End: 7, Count: 1, Symbol: 'F'},
+					}},
+				}},
+			},
+		},
+		{
+			name: "Zero count",
+			src:  "0F(B)",
+			expected: []Node{
+				&Command{Pos: 0, End: 2, Count: 0, Symbol: 'F'},
+				&Group{Pos: 2, End: 5, Count: 1, Body: []Node{
+					&Command{Pos: 3, End: 4, Count: 1, Symbol: 'B'},
+				}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			program, err := Parse(tt.src)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, program.Nodes)
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name           string
+		src            string
+		expectedErr    error
+		expectedOffset int
+	}{
+		{
+			name:           "Unclosed group",
+			src:            "F2(FF",
+			expectedErr:    models.ErrRouteSyntax,
+			expectedOffset: 2,
+		},
+		{
+			name:           "Unexpected closing parenthesis",
+			src:            "FF)",
+			expectedErr:    models.ErrRouteSyntax,
+			expectedOffset: 2,
+		},
+		{
+			name:           "Count without command",
+			src:            "F12",
+			expectedErr:    models.ErrRouteSyntax,
+			expectedOffset: 1,
+		},
+		{
+			name:           "Count before closing parenthesis",
+			src:            "(F3)",
+			expectedErr:    models.ErrRouteSyntax,
+			expectedOffset: 2,
+		},
+		{
+			name:           "Count overflows int",
+			src:            "99999999999999999999F",
+			expectedErr:    models.ErrRouteSyntax,
+			expectedOffset: 0,
+		},
+		{
+			name:           "Route is too long after expansion",
+			src:            "4611686018427387904(FF)",
+			expectedErr:    models.ErrRouteSyntax,
+			expectedOffset: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse(tt.src)
+			require.Error(t, err)
+			assert.ErrorIs(t, err, tt.expectedErr)
+
+			var syntaxErr *SyntaxError
+			require.True(t, errors.As(err, &syntaxErr))
+			assert.Equal(t, tt.expectedOffset, syntaxErr.Offset)
+		})
+	}
+}
+
+func TestParseInvalidSymbols(t *testing.T) {
+	tests := []struct {
+		name     string
+		src      string
+		expected models.SymbolErrors
+	}{
+		{
+			name: "Single symbol",
+			src:  "FFX",
+			expected: models.SymbolErrors{
+				{Offset: 2, Symbol: 'X', Context: "FFX", Caret: 2},
+			},
+		},
+		{
+			name: "All symbols are reported in one pass",
+			src:  "2(FЖ)x(",
+			expected: models.SymbolErrors{
+				{Offset: 3, Symbol: 'Ж', Context: "2(FЖ)x(", Caret: 3},
+				{Offset: 6, Symbol: 'x', Context: "2(FЖ)x(", Caret: 5},
+			},
+		},
+		{
+			name: "Context is cut around the symbol",
+			src:  "FFFFFFFFFFFFFFFFFFFFFFFFFFFFFF X BBBBBBBBBBBBBBBBBBBBBBBBBBBBBB",
+			expected: models.SymbolErrors{
+				{Offset: 30, Symbol: ' ', Context: "FFFFFFFFFFFFFFFFFFFF X BBBBBBBBBBBBBBBBBB", Caret: 20},
+				{Offset: 31, Symbol: 'X', Context: "FFFFFFFFFFFFFFFFFFF X BBBBBBBBBBBBBBBBBBB", Caret: 20},
+				{Offset: 32, Symbol: ' ', Context: "FFFFFFFFFFFFFFFFFF X BBBBBBBBBBBBBBBBBBBB", Caret: 20},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse(tt.src)
+			require.Error(t, err)
+			assert.ErrorIs(t, err, models.ErrIncorrectSymbol)
+
+			var symbolErrs models.SymbolErrors
+			require.ErrorAs(t, err, &symbolErrs)
+			assert.Equal(t, tt.expected, symbolErrs)
+		})
+	}
+}
+
+func TestProgram_Walk(t *testing.T) {
+	type step struct {
+		command rune
+		count   int
+		span    models.Span
+	}
+
+	tests := []struct {
+		name     string
+		src      string
+		expected []step
+	}{
+		{
+			name:     "Commands are passed with counts",
+			src:      "10FL",
+			expected: []step{{'F', 10, models.Span{Start: 0, End: 3}}, {'L', 1, models.Span{Start: 3, End: 4}}},
+		},
+		{
+			name: "Mixed group is unrolled",
+			src:  "2(FR)",
+			expected: []step{
+				{'F', 1, models.Span{Start: 2, End: 3}}, {'R', 1, models.Span{Start: 3, End: 4}},
+				{'F', 1, models.Span{Start: 2, End: 3}}, {'R', 1, models.Span{Start: 3, End: 4}},
+			},
+		},
+		{
+			name:     "Movement group is folded",
+			src:      "L1000000000000(FFB)",
+			expected: []step{{'L', 1, models.Span{Start: 0, End: 1}}, {'F', 1000000000000, models.Span{Start: 1, End: 19}}},
+		},
+		{
+			name:     "Backward movement group is folded",
+			src:      "3(B2(F3B))",
+			expected: []step{{'B', 15, models.Span{Start: 0, End: 10}}},
+		},
+		{
+			name:     "Rotation group is folded modulo four",
+			src:      "1000000001(RR2R)",
+			expected: []step{},
+		},
+		{
+			name:     "Rotation group with remainder",
+			src:      "5(3L)",
+			expected: []step{{'L', 3, models.Span{Start: 0, End: 5}}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			program, err := Parse(tt.src)
+			require.NoError(t, err)
+
+			steps := []step{}
+			program.Walk(func(command rune, count int, span models.Span) bool {
+				steps = append(steps, step{command, count, span})
+				return true
+			})
+			assert.Equal(t, tt.expected, steps)
+		})
+	}
+}
+
+func TestProgram_Each(t *testing.T) {
+	program, err := Parse("2(FB)L")
+	require.NoError(t, err)
+	assert.Equal(t, 5, program.Len())
+
+	var commands []rune
+	program.Each(func(command rune, count int, _ models.Span) bool {
+		commands = append(commands, command)
+		return command != 'L'
+	})
+	assert.Equal(t, []rune{'F', 'B', 'F', 'B', 'L'}, commands)
+}
+
+func TestProgram_Steps(t *testing.T) {
+	tests := []struct {
+		route    string
+		expected int
+	}{
+		{route: "", expected: 0},
+		{route: "FF10LR", expected: 4},
+		{route: "1000000000(FFB)L", expected: 2},
+		{route: "4(LR)", expected: 0},
+		{route: "3(F2(LR)B)", expected: 6},
+		{route: "1000000000000(FR)", expected: 2000000000000},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.route, func(t *testing.T) {
+			program, err := Parse(tt.route)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, program.Steps())
+
+			if tt.expected < 100 {
+				walked := 0
+				program.Walk(func(rune, int, models.Span) bool {
+					walked++
+					return true
+				})
+				assert.Equal(t, walked, program.Steps())
+			}
+		})
+	}
+}
+
+func TestProgram_Count(t *testing.T) {
+	program, err := Parse("3F1000000000000(FBR)2L")
+	require.NoError(t, err)
+
+	assert.Equal(t, 2000000000003, program.Count("FB"))
+	assert.Equal(t, 1000000000002, program.Count("LR"))
+	assert.Equal(t, 1000000000000, program.Count("R"))
+	assert.Equal(t, 0, program.Count(""))
+}
@@ -0,0 +1,67 @@
+package rover
+
+import "mars-rover/internal/models"
+
+// EnergyModel стоимость действий марсохода в единицах заряда батареи
+type EnergyModel interface {
+	// StepCost стоимость проезда одной клетки, backward - движение назад
+	StepCost(backward bool) int
+	// TurnCost стоимость поворота на 90 градусов
+	TurnCost() int
+	// IdleCost стоимость работы систем марсохода, которая списывается за каждую пройденную клетку и каждый
+	// выполненный поворот на 90 градусов. Слияние одинаковых команд в одно движение расход не меняет, а команды,
+	// которые оптимизатор сократил, например FB или LLLL, не выполняются и заряд не расходуют
+	IdleCost() int
+}
+
+// Costs модель энергии с постоянной стоимостью действий
+type Costs struct {
+	Forward  int
+	Backward int
+	Turn     int
+	Idle     int
+}
+
+func (c Costs) StepCost(backward bool) int {
+	if backward {
+		return c.Backward
+	}
+	return c.Forward
+}
+
+func (c Costs) TurnCost() int {
+	return c.Turn
+}
+
+func (c Costs) IdleCost() int {
+	return c.Idle
+}
+
+// Battery батарея марсохода: когда заряда не хватает на следующую клетку или поворот, марсоход останавливается
+type Battery struct {
+	Model EnergyModel
+	// Charge оставшийся заряд
+	Charge int
+}
+
+// NewBattery создаёт батарею с начальным зарядом charge
+func NewBattery(model EnergyModel, charge int) *Battery {
+	return &Battery{Model: model, Charge: charge}
+}
+
+// afford возвращает, на сколько из units единиц действия стоимостью cost хватит заряда. Если заряда не хватает
+// на все единицы, возвращается models.ErrBatteryDepleted. Nil батарея не ограничивает марсоход
+func (b *Battery) afford(units, cost int) (int, error) {
+	if b == nil || cost <= 0 || units <= b.Charge/cost {
+		return units, nil
+	}
+	return max(b.Charge/cost, 0), models.ErrBatteryDepleted
+}
+
+// spend списывает стоимость units выполненных единиц действия
+func (b *Battery) spend(units, cost int) {
+	if b == nil || cost <= 0 {
+		return
+	}
+	b.Charge -= units * cost
+}
@@ -1,28 +1,121 @@
 package rover
 
-import "mars-rover/internal/models"
+import (
+	"mars-rover/internal/models"
+	"mars-rover/internal/plateau"
+)
 
 type Rover struct {
 	Direction models.Direction
 	Pos       models.Coordinates
+	// Plateau плато, по которому ездит марсоход, nil означает бесконечную плоскость
+	Plateau *plateau.Plateau
+	// Recorder получает каждое движение и поворот марсохода, nil отключает трассировку
+	Recorder Recorder
+	// Odometer пробег марсохода с момента создания
+	Odometer Odometer
+	// Battery батарея марсохода, nil означает неограниченный заряд
+	Battery *Battery
+
+	// index номер выполняемого движения маршрута для трассировки, -1 вне PerformRoute
+	index int
+	// spans диапазоны исходных команд движений маршрута для трассировки, nil если они неизвестны
+	spans []models.Span
+}
+
+// Odometer пробег марсохода: клетки, которые он действительно проехал, и повороты
+type Odometer struct {
+	// Forward, Backward количество клеток, пройденных вперёд и назад
+	Forward  int
+	Backward int
+	// Turns количество поворотов на 90 градусов в любую сторону
+	Turns int
+}
+
+// Distance общее количество пройденных клеток
+func (o Odometer) Distance() int {
+	return o.Forward + o.Backward
+}
+
+type Option func(*Rover)
+
+// WithPosition задаёт начальную позицию марсохода
+func WithPosition(pos models.Coordinates) Option {
+	return func(r *Rover) {
+		r.Pos = pos
+	}
+}
+
+// WithDirection задаёт начальное направление марсохода, направление должно быть проверено models.ParseDirection
+func WithDirection(dir models.Direction) Option {
+	return func(r *Rover) {
+		r.Direction = dir
+	}
+}
+
+// WithPlateau задаёт плато, по которому ездит марсоход
+func WithPlateau(p *plateau.Plateau) Option {
+	return func(r *Rover) {
+		r.Plateau = p
+	}
 }
 
-func NewRover() *Rover {
-	return &Rover{
+// WithRecorder включает трассировку движений и поворотов марсохода
+func WithRecorder(rec Recorder) Option {
+	return func(r *Rover) {
+		r.Recorder = rec
+	}
+}
+
+// WithBattery ставит на марсоход батарею с моделью энергии model и начальным зарядом charge
+func WithBattery(model EnergyModel, charge int) Option {
+	return func(r *Rover) {
+		r.Battery = NewBattery(model, charge)
+	}
+}
+
+// NewRover создаёт марсоход, по умолчанию он стоит в (1, 1) и смотрит на север
+func NewRover(opts ...Option) *Rover {
+	r := &Rover{
 		Direction: models.North,
 		Pos:       models.Coordinates{X: 1, Y: 1},
+		index:     -1,
+	}
+	for _, opt := range opts {
+		opt(r)
 	}
+	return r
 }
 
-func (r *Rover) PerformRoute(route []models.Move) {
-	for _, action := range route {
+func (r *Rover) PerformRoute(route []models.Move) error {
+	return r.PerformRouteWithSpans(route, nil)
+}
+
+// PerformRouteWithSpans выполняет маршрут, как PerformRoute, и передаёт в шаги трассировки диапазоны исходных команд
+// spans, из которых получено каждое движение. spans может быть nil
+func (r *Rover) PerformRouteWithSpans(route []models.Move, spans []models.Span) error {
+	r.spans = spans
+	defer func() { r.index, r.spans = -1, nil }()
+	for i, action := range route {
+		r.index = i
+		before := r.Odometer
+		var err error
 		switch action.Type {
 		case models.Movement:
-			r.Move(action.Value)
+			err = r.Move(action.Value)
 		case models.Rotation:
-			r.Rotate(action.Value)
+			err = r.Rotate(action.Value)
+		}
+		if err != nil {
+			done := r.Odometer.Distance() - before.Distance() + r.Odometer.Turns - before.Turns
+			return &models.RouteError{Index: -1, Move: i, Pos: r.Pos, Direction: r.Direction, Done: done, Err: err}
 		}
 	}
+	return nil
+}
+
+func (r *Rover) SetPlateau(p *plateau.Plateau) {
+	r.Plateau = p
 }
 
 func (r *Rover) GetCurrentPosition() models.Coordinates {
@@ -33,20 +126,80 @@ func (r *Rover) GetCurrentDirection() models.Direction {
 	return r.Direction
 }
 
-func (r *Rover) Move(steps int) {
-	switch r.Direction {
-	case models.North:
-		r.Pos.Y += steps
-	case models.South:
-		r.Pos.Y -= steps
-	case models.West:
-		r.Pos.X -= steps
-	case models.East:
-		r.Pos.X += steps
+// Move перемещает марсоход на steps клеток, отрицательное steps - назад. Если батареи не хватает на все клетки,
+// марсоход проезжает столько, на сколько хватило заряда, и возвращает models.ErrBatteryDepleted
+func (r *Rover) Move(steps int) error {
+	from := r.Pos
+	pos, done, err := r.advance(steps)
+
+	if r.Battery != nil {
+		idle := r.Battery.Model.IdleCost()
+		cost := r.Battery.Model.StepCost(steps < 0) + idle
+		// каждая команда F или B оплачивает работу систем, а клетку - только если марсоход её проехал:
+		// в режиме clamp команды на краю выполняются без движения, а на команде, упёршейся
+		// в препятствие или край, маршрут заканчивается
+		rest := steps*sign(steps) - done
+		if err != nil {
+			rest = 1
+		}
+		paid, depleted := r.Battery.afford(done, cost)
+		r.Battery.spend(paid, cost)
+		if depleted != nil {
+			pos, _, _ = r.advance(paid * sign(steps))
+			done, err = paid, depleted
+		} else {
+			paid, depleted = r.Battery.afford(rest, idle)
+			r.Battery.spend(paid, idle)
+			if depleted != nil {
+				err = depleted
+			}
+		}
 	}
+
+	r.Pos = pos
+	steps = done * sign(steps)
+	r.drive(steps)
+	r.record(models.Movement, from, r.Direction, steps, err)
+	return err
 }
 
-func (r *Rover) Rotate(steps int) {
+// advance возвращает, где окажется марсоход после steps шагов без учёта батареи, сколько клеток он проедет
+// и ошибку плато, на которой остановится. Край плато и препятствия сокращают ход, в том числе без ошибки
+// в режиме clamp, поэтому клетки считаются по фактическому смещению. Только на торе без препятствий
+// марсоход проезжает все клетки, даже если сделал полный круг и вернулся на место
+func (r *Rover) advance(steps int) (models.Coordinates, int, error) {
+	dx, dy := offset(r.Direction)
+	if r.Plateau == nil {
+		return models.Coordinates{X: r.Pos.X + dx*steps, Y: r.Pos.Y + dy*steps}, steps * sign(steps), nil
+	}
+	pos, err := r.Plateau.Move(r.Pos, dx, dy, steps)
+	done := steps * sign(steps)
+	if err != nil || r.Plateau.Edge != plateau.EdgeWrap {
+		done = travelled(r.Plateau, r.Pos, pos, dx*sign(steps), dy*sign(steps))
+	}
+	return pos, done, err
+}
+
+// drive добавляет в одометр клетки, которые марсоход проехал, steps отрицательное при движении назад
+func (r *Rover) drive(steps int) {
+	if steps >= 0 {
+		r.Odometer.Forward += steps
+	} else {
+		r.Odometer.Backward -= steps
+	}
+}
+
+// Rotate поворачивает марсоход на steps раз по 90 градусов, положительное steps - налево.
+// Если батареи не хватает, марсоход поворачивается, насколько хватило заряда, и возвращает models.ErrBatteryDepleted
+func (r *Rover) Rotate(steps int) error {
+	cost := 0
+	if r.Battery != nil {
+		cost = r.Battery.Model.TurnCost() + r.Battery.Model.IdleCost()
+	}
+	affordable, depleted := r.Battery.afford(steps*sign(steps), cost)
+	steps = affordable * sign(steps)
+	r.Battery.spend(affordable, cost)
+
 	directions := []models.Direction{models.North, models.West, models.South, models.East}
 	currentIndex := indexOf(r.Direction, directions)
 	newIndex := (currentIndex + steps) % len(directions)
@@ -54,7 +207,55 @@ func (r *Rover) Rotate(steps int) {
 		newIndex += len(directions)
 	}
 
+	heading := r.Direction
 	r.Direction = directions[newIndex]
+	r.Odometer.Turns += steps * sign(steps)
+	r.record(models.Rotation, r.Pos, heading, steps, depleted)
+	return depleted
+}
+
+// record передаёт шаг в Recorder, если трассировка включена
+func (r *Rover) record(moveType models.MoveType, from models.Coordinates, heading models.Direction, value int, err error) {
+	if r.Recorder == nil {
+		return
+	}
+	var span models.Span
+	if r.index >= 0 && r.index < len(r.spans) {
+		span = r.spans[r.index]
+	}
+	r.Recorder.Record(Step{
+		Index:     r.index,
+		Span:      span,
+		Type:      moveType,
+		From:      from,
+		To:        r.Pos,
+		Heading:   heading,
+		Direction: r.Direction,
+		Value:     value,
+		Err:       err,
+	})
+}
+
+func sign(v int) int {
+	if v < 0 {
+		return -1
+	}
+	return 1
+}
+
+// offset возвращает смещение по осям при шаге вперёд в направлении dir
+func offset(dir models.Direction) (int, int) {
+	switch dir {
+	case models.North:
+		return 0, 1
+	case models.South:
+		return 0, -1
+	case models.West:
+		return -1, 0
+	case models.East:
+		return 1, 0
+	}
+	return 0, 0
 }
 
 // todo move to some common package...
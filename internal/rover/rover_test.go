@@ -1,8 +1,12 @@
 package rover
 
 import (
+	"errors"
+	"fmt"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"mars-rover/internal/models"
+	"mars-rover/internal/plateau"
 	"testing"
 )
 
@@ -12,6 +16,14 @@ func TestNewRover(t *testing.T) {
 	assert.Equal(t, models.Coordinates{X: 1, Y: 1}, r.Pos)
 }
 
+func TestNewRoverWithOptions(t *testing.T) {
+	p := &plateau.Plateau{Width: 10, Height: 10}
+	r := NewRover(WithPosition(models.Coordinates{X: 3, Y: 7}), WithDirection(models.West), WithPlateau(p))
+	assert.Equal(t, models.West, r.Direction)
+	assert.Equal(t, models.Coordinates{X: 3, Y: 7}, r.Pos)
+	assert.Equal(t, p, r.Plateau)
+}
+
 func TestRover_Move(t *testing.T) {
 	tests := []struct {
 		name      string
@@ -149,13 +161,303 @@ func TestRover_PerformRoute(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			tt.initial.PerformRoute(tt.route)
+			require.NoError(t, tt.initial.PerformRoute(tt.route))
 			assert.Equal(t, tt.expectedPos, tt.initial.GetCurrentPosition())
 			assert.Equal(t, tt.expectedDir, tt.initial.GetCurrentDirection())
 		})
 	}
 }
 
+func TestRover_PerformRouteOnPlateau(t *testing.T) {
+	route := []models.Move{
+		{Type: models.Movement, Value: 2},  // (1, 3), N
+		{Type: models.Rotation, Value: -1}, // (1, 3), E
+		{Type: models.Movement, Value: 5},  // за восточный край
+		{Type: models.Rotation, Value: 1},  // (?, 3), N
+		{Type: models.Movement, Value: 1},
+	}
+
+	tests := []struct {
+		name         string
+		edge         plateau.EdgeMode
+		expectedPos  models.Coordinates
+		expectedDir  models.Direction
+		expectedMove int
+		expectError  bool
+	}{
+		{"Reject", plateau.EdgeReject, models.Coordinates{X: 4, Y: 3}, models.East, 2, true},
+		{"Clamp", plateau.EdgeClamp, models.Coordinates{X: 4, Y: 4}, models.North, 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewRover()
+			r.Plateau = &plateau.Plateau{Width: 5, Height: 5, Edge: tt.edge}
+
+			err := r.PerformRoute(route)
+			if tt.expectError {
+				var routeErr *models.RouteError
+				require.ErrorAs(t, err, &routeErr)
+				assert.ErrorIs(t, err, models.ErrOutOfBounds)
+				assert.Equal(t, tt.expectedMove, routeErr.Move)
+				assert.Equal(t, tt.expectedPos, routeErr.Pos)
+			} else {
+				require.NoError(t, err)
+			}
+			assert.Equal(t, tt.expectedPos, r.GetCurrentPosition())
+			assert.Equal(t, tt.expectedDir, r.GetCurrentDirection())
+		})
+	}
+}
+
+func TestRover_PerformRouteWithObstacles(t *testing.T) {
+	r := NewRover()
+	r.SetPlateau(&plateau.Plateau{Obstacles: plateau.NewObstacles(models.Coordinates{X: 3, Y: 3})})
+
+	// FFRFFFF: после оптимизации второй отрезок схлопнут в одно движение на 4 клетки,
+	// которое проходит через препятствие в (3, 3)
+	err := r.PerformRoute([]models.Move{
+		{Type: models.Movement, Value: 2},
+		{Type: models.Rotation, Value: -1},
+		{Type: models.Movement, Value: 4},
+	})
+
+	var routeErr *models.RouteError
+	require.ErrorAs(t, err, &routeErr)
+	assert.ErrorIs(t, err, models.ErrObstacle)
+	assert.Equal(t, 2, routeErr.Move)
+	assert.Equal(t, -1, routeErr.Index)
+	assert.Equal(t, models.Coordinates{X: 2, Y: 3}, routeErr.Pos)
+	assert.Equal(t, models.East, routeErr.Direction)
+	assert.Equal(t, models.Coordinates{X: 2, Y: 3}, r.GetCurrentPosition())
+}
+
+func TestRover_Odometer(t *testing.T) {
+	r := NewRover(WithPlateau(&plateau.Plateau{Obstacles: plateau.NewObstacles(models.Coordinates{X: -5, Y: 3})}))
+
+	err := r.PerformRoute([]models.Move{
+		{Type: models.Movement, Value: 2},
+		{Type: models.Rotation, Value: -1},
+		{Type: models.Movement, Value: -3},
+		{Type: models.Rotation, Value: 2},
+		{Type: models.Movement, Value: 5},
+	})
+
+	// последнее движение остановилось перед препятствием в (-5, 3) через 2 клетки из 5
+	require.ErrorIs(t, err, models.ErrObstacle)
+	assert.Equal(t, Odometer{Forward: 4, Backward: 3, Turns: 3}, r.Odometer)
+	assert.Equal(t, 7, r.Odometer.Distance())
+}
+
+func TestRover_OdometerOnPlateauEdge(t *testing.T) {
+	tests := []struct {
+		name     string
+		edge     plateau.EdgeMode
+		steps    int
+		expected Odometer
+	}{
+		{"Clamp at the edge", plateau.EdgeClamp, 5, Odometer{}},
+		{"Clamp backwards", plateau.EdgeClamp, -10, Odometer{Backward: 4}},
+		{"Reject at the edge", plateau.EdgeReject, 5, Odometer{}},
+		{"Full lap on torus", plateau.EdgeWrap, 5, Odometer{Forward: 5}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewRover(
+				WithPosition(models.Coordinates{X: 1, Y: 4}),
+				WithPlateau(&plateau.Plateau{Width: 5, Height: 5, Edge: tt.edge}),
+				WithBattery(Costs{Forward: 1, Backward: 1}, 100),
+			)
+			_ = r.Move(tt.steps)
+			assert.Equal(t, tt.expected, r.Odometer)
+			assert.Equal(t, 100-tt.expected.Distance(), r.Battery.Charge)
+		})
+	}
+}
+
+func TestRover_Battery(t *testing.T) {
+	costs := Costs{Forward: 1, Backward: 2, Turn: 3, Idle: 1}
+	tests := []struct {
+		name         string
+		charge       int
+		route        []models.Move
+		expectedMove int
+		expectedPos  models.Coordinates
+		expectedDir  models.Direction
+		expectedLeft int
+	}{
+		{
+			name:         "Enough charge",
+			charge:       16,
+			route:        []models.Move{{Type: models.Movement, Value: 3}, {Type: models.Rotation, Value: 1}, {Type: models.Movement, Value: -2}},
+			expectedMove: -1,
+			expectedPos:  models.Coordinates{X: 3, Y: 4},
+			expectedDir:  models.West,
+			expectedLeft: 0,
+		},
+		{
+			name:         "Depleted during movement",
+			charge:       5,
+			route:        []models.Move{{Type: models.Movement, Value: 5}},
+			expectedMove: 0,
+			expectedPos:  models.Coordinates{X: 1, Y: 3},
+			expectedDir:  models.North,
+			expectedLeft: 1,
+		},
+		{
+			name:         "Depleted during backward movement",
+			charge:       7,
+			route:        []models.Move{{Type: models.Movement, Value: -1_000_000_000_000}},
+			expectedMove: 0,
+			expectedPos:  models.Coordinates{X: 1, Y: -1},
+			expectedDir:  models.North,
+			expectedLeft: 1,
+		},
+		{
+			name:         "Depleted during rotation",
+			charge:       11,
+			route:        []models.Move{{Type: models.Movement, Value: 1}, {Type: models.Rotation, Value: -3}},
+			expectedMove: 1,
+			expectedPos:  models.Coordinates{X: 1, Y: 2},
+			expectedDir:  models.South,
+			expectedLeft: 1,
+		},
+		{
+			name:         "No charge for idle cost",
+			charge:       0,
+			route:        []models.Move{{Type: models.Rotation, Value: 1}},
+			expectedMove: 0,
+			expectedPos:  models.Coordinates{X: 1, Y: 1},
+			expectedDir:  models.North,
+			expectedLeft: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewRover(WithBattery(costs, tt.charge))
+			err := r.PerformRoute(tt.route)
+
+			if tt.expectedMove < 0 {
+				require.NoError(t, err)
+			} else {
+				var routeErr *models.RouteError
+				require.ErrorAs(t, err, &routeErr)
+				assert.ErrorIs(t, err, models.ErrBatteryDepleted)
+				assert.Equal(t, tt.expectedMove, routeErr.Move)
+				assert.Equal(t, tt.expectedPos, routeErr.Pos)
+			}
+			assert.Equal(t, tt.expectedPos, r.Pos)
+			assert.Equal(t, tt.expectedDir, r.Direction)
+			assert.Equal(t, tt.expectedLeft, r.Battery.Charge)
+		})
+	}
+}
+
+// TestRover_BatteryDoesNotDependOnCollapsing проверяет, что схлопнутый маршрут расходует столько же заряда
+// и останавливается там же, где маршрут, выполненный по одной команде
+// TestRover_BatteryDoesNotDependOnMerging проверяет, что движение из слитых одинаковых команд расходует заряд
+// так же, как эти команды по одной. Сокращение противоположных команд проверяется в пакете app
+func TestRover_BatteryDoesNotDependOnMerging(t *testing.T) {
+	costs := Costs{Forward: 1, Backward: 2, Turn: 3, Idle: 1}
+	route := "FFFFRFFFFFBBLLL"
+
+	var collapsed []models.Move
+	for i := 0; i < len(route); {
+		j := i
+		for j < len(route) && route[j] == route[i] {
+			j++
+		}
+		collapsed = append(collapsed, command(route[i], j-i))
+		i = j
+	}
+
+	for _, edge := range []plateau.EdgeMode{plateau.EdgeReject, plateau.EdgeClamp, plateau.EdgeWrap} {
+		p := &plateau.Plateau{Width: 5, Height: 5, Edge: edge, Obstacles: plateau.NewObstacles(models.Coordinates{X: 3, Y: 4})}
+		for charge := 0; charge <= 40; charge += 3 {
+			t.Run(fmt.Sprintf("%s/%d", edge, charge), func(t *testing.T) {
+				start := []Option{WithPosition(models.Coordinates{X: 1, Y: 3}), WithPlateau(p), WithBattery(costs, charge)}
+
+				single := NewRover(start...)
+				var singleErr error
+				for i := 0; i < len(route) && singleErr == nil; i++ {
+					singleErr = single.PerformRoute([]models.Move{command(route[i], 1)})
+				}
+
+				merged := NewRover(start...)
+				mergedErr := merged.PerformRoute(collapsed)
+
+				assert.Equal(t, single.Pos, merged.Pos)
+				assert.Equal(t, single.Direction, merged.Direction)
+				assert.Equal(t, single.Battery.Charge, merged.Battery.Charge)
+				assert.Equal(t, errors.Is(singleErr, models.ErrBatteryDepleted), errors.Is(mergedErr, models.ErrBatteryDepleted))
+				assert.Equal(t, singleErr == nil, mergedErr == nil)
+			})
+		}
+	}
+}
+
+// command движение из count одинаковых команд F, B, L или R
+func command(symbol byte, count int) models.Move {
+	switch symbol {
+	case 'F':
+		return models.Move{Type: models.Movement, Value: count}
+	case 'B':
+		return models.Move{Type: models.Movement, Value: -count}
+	case 'L':
+		return models.Move{Type: models.Rotation, Value: count}
+	default:
+		return models.Move{Type: models.Rotation, Value: -count}
+	}
+}
+
+func TestRover_BatteryStopsBeforeObstacle(t *testing.T) {
+	trace := NewTrace()
+	r := NewRover(
+		WithPlateau(&plateau.Plateau{Obstacles: plateau.NewObstacles(models.Coordinates{X: 1, Y: 3})}),
+		WithBattery(Costs{Forward: 1}, 10),
+		WithRecorder(trace),
+	)
+
+	// препятствие встречается раньше, чем кончается заряд: списываются только пройденные клетки
+	err := r.Move(5)
+	assert.ErrorIs(t, err, models.ErrObstacle)
+	assert.Equal(t, 9, r.Battery.Charge)
+	require.Len(t, trace.Steps, 1)
+	assert.Equal(t, 1, trace.Steps[0].Value)
+}
+
+func TestRover_MoveOnTorus(t *testing.T) {
+	tests := []struct {
+		name      string
+		initial   models.Coordinates
+		direction models.Direction
+		steps     int
+		expected  models.Coordinates
+	}{
+		{"Leave east edge", models.Coordinates{X: 4, Y: 2}, models.East, 1, models.Coordinates{X: 0, Y: 2}},
+		{"Leave west edge", models.Coordinates{X: 0, Y: 2}, models.West, 1, models.Coordinates{X: 4, Y: 2}},
+		{"Leave north edge", models.Coordinates{X: 2, Y: 2}, models.North, 3, models.Coordinates{X: 2, Y: 0}},
+		{"Leave south edge backwards", models.Coordinates{X: 2, Y: 2}, models.North, -3, models.Coordinates{X: 2, Y: 4}},
+		{"Full lap", models.Coordinates{X: 2, Y: 2}, models.East, 5, models.Coordinates{X: 2, Y: 2}},
+		{"Huge forward step count", models.Coordinates{X: 2, Y: 2}, models.East, 1_000_000_000_000_000_001, models.Coordinates{X: 3, Y: 2}},
+		{"Huge backward step count", models.Coordinates{X: 2, Y: 2}, models.South, -1_000_000_000_000_000_002, models.Coordinates{X: 2, Y: 4}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := Rover{
+				Direction: tt.direction,
+				Pos:       tt.initial,
+				Plateau:   &plateau.Plateau{Width: 5, Height: 5, Edge: plateau.EdgeWrap},
+			}
+			require.NoError(t, r.Move(tt.steps))
+			assert.Equal(t, tt.expected, r.Pos)
+		})
+	}
+}
+
 func TestIndexOf(t *testing.T) {
 	directions := []models.Direction{models.North, models.West, models.South, models.East}
 	tests := []struct {
@@ -177,3 +479,199 @@ func TestIndexOf(t *testing.T) {
 		})
 	}
 }
+
+func TestRover_Trace(t *testing.T) {
+	obstacles := plateau.NewObstacles(models.Coordinates{X: 1, Y: 4}, models.Coordinates{X: 1, Y: 0})
+
+	tests := []struct {
+		name     string
+		plateau  *plateau.Plateau
+		start    models.Coordinates
+		dir      models.Direction
+		route    []models.Move
+		expected []Step
+	}{
+		{
+			name:  "Moves and turns",
+			start: models.Coordinates{X: 1, Y: 1},
+			dir:   models.North,
+			route: []models.Move{
+				{Type: models.Movement, Value: 2},
+				{Type: models.Rotation, Value: 1},
+				{Type: models.Movement, Value: -1},
+			},
+			expected: []Step{
+				{Index: 0, Type: models.Movement, From: models.Coordinates{X: 1, Y: 1}, To: models.Coordinates{X: 1, Y: 3},
+					Heading: models.North, Direction: models.North, Value: 2},
+				{Index: 1, Type: models.Rotation, From: models.Coordinates{X: 1, Y: 3}, To: models.Coordinates{X: 1, Y: 3},
+					Heading: models.North, Direction: models.West, Value: 1},
+				{Index: 2, Type: models.Movement, From: models.Coordinates{X: 1, Y: 3}, To: models.Coordinates{X: 2, Y: 3},
+					Heading: models.West, Direction: models.West, Value: -1},
+			},
+		},
+		{
+			name:    "Stop before obstacle",
+			plateau: &plateau.Plateau{Obstacles: obstacles},
+			start:   models.Coordinates{X: 1, Y: 1},
+			dir:     models.North,
+			route:   []models.Move{{Type: models.Movement, Value: 5}},
+			expected: []Step{
+				{Index: 0, Type: models.Movement, From: models.Coordinates{X: 1, Y: 1}, To: models.Coordinates{X: 1, Y: 3},
+					Heading: models.North, Direction: models.North, Value: 2, Err: models.ErrObstacle},
+			},
+		},
+		{
+			name:    "Stop before obstacle moving backward on the torus",
+			plateau: &plateau.Plateau{Width: 5, Height: 5, Edge: plateau.EdgeWrap, Obstacles: obstacles},
+			start:   models.Coordinates{X: 1, Y: 3},
+			dir:     models.South,
+			route:   []models.Move{{Type: models.Movement, Value: -3}},
+			expected: []Step{
+				{Index: 0, Type: models.Movement, From: models.Coordinates{X: 1, Y: 3}, To: models.Coordinates{X: 1, Y: 3},
+					Heading: models.South, Direction: models.South, Value: 0, Err: models.ErrObstacle},
+			},
+		},
+		{
+			name: "Stop before obstacle behind the torus edge",
+			plateau: &plateau.Plateau{Width: 5, Height: 5, Edge: plateau.EdgeWrap,
+				Obstacles: plateau.NewObstacles(models.Coordinates{X: 1, Y: 2})},
+			start: models.Coordinates{X: 3, Y: 2},
+			dir:   models.East,
+			route: []models.Move{{Type: models.Movement, Value: 5}},
+			expected: []Step{
+				{Index: 0, Type: models.Movement, From: models.Coordinates{X: 3, Y: 2}, To: models.Coordinates{X: 0, Y: 2},
+					Heading: models.East, Direction: models.East, Value: 2, Err: models.ErrObstacle},
+			},
+		},
+		{
+			name:    "Rejected move stops at the edge",
+			plateau: &plateau.Plateau{Width: 3, Height: 3},
+			start:   models.Coordinates{X: 1, Y: 1},
+			dir:     models.East,
+			route:   []models.Move{{Type: models.Movement, Value: 5}},
+			expected: []Step{
+				{Index: 0, Type: models.Movement, From: models.Coordinates{X: 1, Y: 1}, To: models.Coordinates{X: 2, Y: 1},
+					Heading: models.East, Direction: models.East, Value: 1, Err: models.ErrOutOfBounds},
+			},
+		},
+		{
+			name:    "Clamped move records cells actually passed",
+			plateau: &plateau.Plateau{Width: 3, Height: 3, Edge: plateau.EdgeClamp},
+			start:   models.Coordinates{X: 1, Y: 1},
+			dir:     models.West,
+			route:   []models.Move{{Type: models.Movement, Value: -4}, {Type: models.Movement, Value: 3}},
+			expected: []Step{
+				{Index: 0, Type: models.Movement, From: models.Coordinates{X: 1, Y: 1}, To: models.Coordinates{X: 2, Y: 1},
+					Heading: models.West, Direction: models.West, Value: -1},
+				{Index: 1, Type: models.Movement, From: models.Coordinates{X: 2, Y: 1}, To: models.Coordinates{X: 0, Y: 1},
+					Heading: models.West, Direction: models.West, Value: 2},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			trace := NewTrace()
+			r := NewRover(WithPosition(tt.start), WithDirection(tt.dir), WithPlateau(tt.plateau), WithRecorder(trace))
+			_ = r.PerformRoute(tt.route)
+
+			require.Len(t, trace.Steps, len(tt.expected))
+			for i, step := range trace.Steps {
+				// клетки шага заканчиваются там, где марсоход действительно оказался
+				last := step.From
+				step.Cells(tt.plateau, func(cell models.Coordinates) bool {
+					last = cell
+					return true
+				})
+				assert.Equal(t, step.To, last)
+
+				expected := tt.expected[i]
+				if expected.Err != nil {
+					assert.ErrorIs(t, step.Err, expected.Err)
+					step.Err, expected.Err = nil, nil
+				}
+				assert.Equal(t, expected, step)
+			}
+		})
+	}
+}
+
+func TestRover_TraceDirectCalls(t *testing.T) {
+	trace := NewTrace()
+	r := NewRover(WithRecorder(trace))
+	require.NoError(t, r.Move(1))
+	r.Rotate(-1)
+
+	require.Len(t, trace.Steps, 2)
+	assert.Equal(t, -1, trace.Steps[0].Index)
+	assert.Equal(t, -1, trace.Steps[1].Index)
+	assert.Equal(t, models.East, trace.Steps[1].Direction)
+}
+
+func TestRover_TraceSpans(t *testing.T) {
+	trace := NewTrace()
+	r := NewRover(WithRecorder(trace))
+	// маршрут RLFFFBBL: повороты RL сократились, FFFBB схлопнулось в одну клетку вперёд
+	require.NoError(t, r.PerformRouteWithSpans(
+		[]models.Move{{Type: models.Movement, Value: 1}, {Type: models.Rotation, Value: 1}},
+		[]models.Span{{Start: 2, End: 7}, {Start: 7, End: 8}},
+	))
+	require.NoError(t, r.Move(1))
+
+	require.Len(t, trace.Steps, 3)
+	assert.Equal(t, 0, trace.Steps[0].Index)
+	assert.Equal(t, models.Span{Start: 2, End: 7}, trace.Steps[0].Span)
+	assert.Equal(t, 1, trace.Steps[1].Index)
+	assert.Equal(t, models.Span{Start: 7, End: 8}, trace.Steps[1].Span)
+	// после маршрута диапазоны не переносятся на прямые вызовы
+	assert.Equal(t, models.Span{}, trace.Steps[2].Span)
+}
+
+func TestStep_Cells(t *testing.T) {
+	tests := []struct {
+		name     string
+		plateau  *plateau.Plateau
+		step     Step
+		expected []models.Coordinates
+	}{
+		{
+			name:     "Forward on the plane",
+			step:     Step{Type: models.Movement, From: models.Coordinates{X: 1, Y: 1}, Heading: models.North, Value: 2},
+			expected: []models.Coordinates{{X: 1, Y: 2}, {X: 1, Y: 3}},
+		},
+		{
+			name:     "Backward",
+			step:     Step{Type: models.Movement, From: models.Coordinates{X: 1, Y: 1}, Heading: models.East, Value: -2},
+			expected: []models.Coordinates{{X: 0, Y: 1}, {X: -1, Y: 1}},
+		},
+		{
+			name:     "Across the torus edge",
+			plateau:  &plateau.Plateau{Width: 5, Height: 5, Edge: plateau.EdgeWrap},
+			step:     Step{Type: models.Movement, From: models.Coordinates{X: 4, Y: 0}, Heading: models.East, Value: 3},
+			expected: []models.Coordinates{{X: 0, Y: 0}, {X: 1, Y: 0}, {X: 2, Y: 0}},
+		},
+		{
+			name: "Turn visits no cells",
+			step: Step{Type: models.Rotation, From: models.Coordinates{X: 4, Y: 0}, Heading: models.East, Value: 1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var cells []models.Coordinates
+			tt.step.Cells(tt.plateau, func(cell models.Coordinates) bool {
+				cells = append(cells, cell)
+				return true
+			})
+			assert.Equal(t, tt.expected, cells)
+		})
+	}
+
+	var first []models.Coordinates
+	huge := Step{Type: models.Movement, Heading: models.North, Value: 1_000_000_000_000}
+	huge.Cells(nil, func(cell models.Coordinates) bool {
+		first = append(first, cell)
+		return len(first) < 2
+	})
+	assert.Equal(t, []models.Coordinates{{X: 0, Y: 1}, {X: 0, Y: 2}}, first)
+}
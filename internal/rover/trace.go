@@ -0,0 +1,83 @@
+package rover
+
+import (
+	"mars-rover/internal/models"
+	"mars-rover/internal/plateau"
+)
+
+// Step запись трассировки: одно движение или один поворот марсохода
+type Step struct {
+	// Index номер движения в оптимизированном маршруте, переданном в PerformRoute, -1 для прямых вызовов Move и Rotate
+	Index int
+	// Span команды исходного маршрута, из которых получено движение, нулевой, если они неизвестны:
+	// диапазоны передаёт PerformRouteWithSpans
+	Span models.Span
+	Type models.MoveType
+	// From, To позиция марсохода до и после шага
+	From models.Coordinates
+	To   models.Coordinates
+	// Heading направление марсохода до шага, Direction после
+	Heading   models.Direction
+	Direction models.Direction
+	// Value для движения количество клеток, которые марсоход действительно проехал, отрицательное при движении назад,
+	// для поворота количество поворотов на 90 градусов против часовой стрелки
+	Value int
+	// Err причина, по которой движение выполнено не полностью
+	Err error
+}
+
+// Cells перебирает клетки, которые марсоход проехал за шаг, не включая начальную, пока yield возвращает true.
+// p плато, на котором выполнялся маршрут, nil для бесконечной плоскости
+func (s Step) Cells(p *plateau.Plateau, yield func(models.Coordinates) bool) {
+	if s.Type != models.Movement {
+		return
+	}
+	dx, dy := offset(s.Heading)
+	steps := s.Value
+	if steps < 0 {
+		dx, dy, steps = -dx, -dy, -steps
+	}
+
+	pos := s.From
+	for i := 0; i < steps; i++ {
+		if p == nil {
+			pos = models.Coordinates{X: pos.X + dx, Y: pos.Y + dy}
+		} else {
+			pos, _ = p.Move(pos, dx, dy, 1)
+		}
+		if !yield(pos) {
+			return
+		}
+	}
+}
+
+// Recorder получает шаги марсохода по мере их выполнения
+type Recorder interface {
+	Record(step Step)
+}
+
+// Trace записывает шаги марсохода в память
+type Trace struct {
+	Steps []Step
+}
+
+func NewTrace() *Trace {
+	return &Trace{}
+}
+
+func (t *Trace) Record(step Step) {
+	t.Steps = append(t.Steps, step)
+}
+
+// travelled возвращает количество клеток, которые марсоход проехал от from до to вдоль вектора (dx, dy)
+func travelled(p *plateau.Plateau, from, to models.Coordinates, dx, dy int) int {
+	distance := (to.X-from.X)*dx + (to.Y-from.Y)*dy
+	size := p.Height
+	if dx != 0 {
+		size = p.Width
+	}
+	if p.Edge == plateau.EdgeWrap && size > 0 {
+		distance = (distance%size + size) % size
+	}
+	return distance
+}
@@ -0,0 +1,197 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"mars-rover/internal/api"
+	"mars-rover/internal/app"
+	"mars-rover/internal/rover"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// controlPath путь WebSocket управления марсоходом
+	controlPath = "/api/v1/control"
+	// maxControlMessage ограничение на размер команды от клиента
+	maxControlMessage = 512
+	// closeTimeout сколько ждать отправки кадра закрытия соединения
+	closeTimeout = time.Second
+)
+
+// ControlMessage сообщение, которое WebSocket управления отправляет клиенту после каждой команды
+type ControlMessage struct {
+	// Command команда клиента, пустая в первом сообщении после подключения
+	Command   string       `json:"command,omitempty"`
+	Message   string       `json:"message"`
+	Position  api.Position `json:"position"`
+	Direction string       `json:"direction"`
+	// Error код ошибки, если марсоход не смог выполнить команду
+	Error string `json:"error,omitempty"`
+}
+
+// watchedRover запоминает ошибку последнего движения марсохода, чтобы сообщить клиенту её код:
+// InteractiveControl возвращает только текст для человека
+type watchedRover struct {
+	app.Rover
+	err error
+}
+
+func (r *watchedRover) Move(steps int) error {
+	r.err = r.Rover.Move(steps)
+	return r.err
+}
+
+// driver марсоход, которым удалённый клиент управляет командами app.App.InteractiveControl.
+// InteractiveControl отвечает на каждую команду одним сообщением и ждёт следующую, поэтому после ответа
+// марсоход можно читать без блокировок. Методы driver нельзя вызывать одновременно
+type driver struct {
+	rover   *rover.Rover
+	watched *watchedRover
+	input   chan string
+	output  chan string
+}
+
+// newDriver создаёт марсоход в начальном состоянии start и запускает для него InteractiveControl
+func (s *Server) newDriver(start *Start, strategy string) (*driver, *api.Error) {
+	a, rv, e := s.newApp(start, strategy)
+	if e != nil {
+		return nil, e
+	}
+	d := &driver{
+		rover:   rv,
+		watched: &watchedRover{Rover: rv},
+		input:   make(chan string),
+		output:  make(chan string),
+	}
+	a.Rover = d.watched
+	go func() {
+		_ = a.InteractiveControl(d.input, d.output)
+	}()
+	return d, nil
+}
+
+// do выполняет команду и возвращает ответ, false если команда exit завершила управление
+func (d *driver) do(command string) (ControlMessage, bool) {
+	d.watched.err = nil
+	d.input <- command
+	message, ok := <-d.output
+	if !ok {
+		return ControlMessage{}, false
+	}
+	return d.state(command, message), true
+}
+
+// state текущее состояние марсохода с ответом message на команду command
+func (d *driver) state(command, message string) ControlMessage {
+	msg := ControlMessage{
+		Command:   command,
+		Message:   message,
+		Position:  api.Position{X: d.rover.Pos.X, Y: d.rover.Pos.Y},
+		Direction: string(d.rover.Direction),
+	}
+	if d.watched.err != nil {
+		msg.Error = api.ErrorCode(d.watched.err)
+	}
+	return msg
+}
+
+// close останавливает InteractiveControl, если клиент отключился без команды exit
+func (d *driver) close() {
+	close(d.input)
+}
+
+var upgrader = websocket.Upgrader{ReadBufferSize: 1024, WriteBufferSize: 1024}
+
+// handleControl управляет марсоходом через WebSocket: GET /api/v1/control?x=1&y=1&direction=N&optimizer=minimal.
+// Клиент отправляет текстовые сообщения с командами app.App.InteractiveControl (up, down, left, right, undo, redo,
+// checkpoint имя, restore имя, exit), сервер отвечает на каждую команду ControlMessage в JSON.
+// Для каждого подключения создаётся свой марсоход
+func (s *Server) handleControl(w http.ResponseWriter, r *http.Request) {
+	if !allow(w, r, http.MethodGet) {
+		return
+	}
+	start, e := parseStart(r)
+	if e != nil {
+		writeError(w, http.StatusBadRequest, e)
+		return
+	}
+	d, e := s.newDriver(start, r.URL.Query().Get("optimizer"))
+	if e != nil {
+		writeError(w, http.StatusBadRequest, e)
+		return
+	}
+	defer d.close()
+
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// Upgrade уже ответил клиенту
+		return
+	}
+	defer conn.Close()
+	conn.SetReadLimit(maxControlMessage)
+
+	// при остановке сервера клиенту отправляется кадр закрытия, после него ReadMessage возвращает ошибку
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+	go func() {
+		<-ctx.Done()
+		closeConn(conn, websocket.CloseGoingAway, "server is shutting down")
+	}()
+
+	if err := conn.WriteJSON(d.state("", "Марсоход на связи")); err != nil {
+		return
+	}
+	for {
+		kind, data, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		if kind != websocket.TextMessage {
+			continue
+		}
+
+		msg, ok := d.do(string(data))
+		if !ok {
+			closeConn(conn, websocket.CloseNormalClosure, "exit")
+			return
+		}
+		if err := conn.WriteJSON(msg); err != nil {
+			return
+		}
+	}
+}
+
+// parseStart разбирает начальное состояние марсохода из параметров x, y и direction, nil если их нет
+func parseStart(r *http.Request) (*Start, *api.Error) {
+	query := r.URL.Query()
+	if !query.Has("x") && !query.Has("y") && !query.Has("direction") {
+		return nil, nil
+	}
+
+	start := &Start{X: 1, Y: 1, Direction: query.Get("direction")}
+	for name, dst := range map[string]*int{"x": &start.X, "y": &start.Y} {
+		if !query.Has(name) {
+			continue
+		}
+		v, err := strconv.Atoi(query.Get(name))
+		if err != nil {
+			return nil, &api.Error{
+				Code: api.CodeInvalidStart, Message: "Координаты должны быть целыми числами", Detail: err.Error(),
+			}
+		}
+		*dst = v
+	}
+	return start, nil
+}
+
+func closeConn(conn *websocket.Conn, code int, reason string) {
+	err := conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, reason),
+		time.Now().Add(closeTimeout))
+	if err != nil && !errors.Is(err, websocket.ErrCloseSent) {
+		_ = conn.Close()
+	}
+}
@@ -0,0 +1,105 @@
+package server
+
+import (
+	"context"
+	"mars-rover/internal/api"
+	"mars-rover/internal/models"
+	"mars-rover/internal/plateau"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func dialControl(t *testing.T, url string) *websocket.Conn {
+	t.Helper()
+	conn, resp, err := websocket.DefaultDialer.Dial(url, nil)
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+	t.Cleanup(func() { _ = conn.Close() })
+	return conn
+}
+
+func TestServer_Control(t *testing.T) {
+	p := &plateau.Plateau{Obstacles: plateau.NewObstacles(models.Coordinates{X: 0, Y: 2})}
+	ts := httptest.NewServer(NewServer(WithPlateau(p)))
+	defer ts.Close()
+
+	conn := dialControl(t, "ws"+strings.TrimPrefix(ts.URL, "http")+"/api/v1/control?x=0&y=0")
+
+	var msg ControlMessage
+	require.NoError(t, conn.ReadJSON(&msg))
+	assert.Equal(t, ControlMessage{Message: "Марсоход на связи", Position: api.Position{X: 0, Y: 0}, Direction: "N"}, msg)
+
+	steps := []ControlMessage{
+		{Command: "up", Message: "Текущие координаты: (0, 1), направление: N", Position: api.Position{X: 0, Y: 1}, Direction: "N"},
+		{
+			Command: "up",
+			Message: "Марсоход остановился перед препятствием на команде с индексом 1. " +
+				"Последняя безопасная точка (0, 1), направление: N",
+			Position:  api.Position{X: 0, Y: 1},
+			Direction: "N",
+			Error:     api.CodeObstacle,
+		},
+		{Command: "right", Message: "Текущие координаты: (0, 1), направление: E", Position: api.Position{X: 0, Y: 1}, Direction: "E"},
+		{
+			Command:   "undo",
+			Message:   "Команда отменена. Текущие координаты: (0, 1), направление: N",
+			Position:  api.Position{X: 0, Y: 1},
+			Direction: "N",
+		},
+		{
+			Command:   "jump",
+			Message:   "Некорректная команда jump, используйте стрелки вверх, вниз, влево, вправо.",
+			Position:  api.Position{X: 0, Y: 1},
+			Direction: "N",
+		},
+	}
+	for _, expected := range steps {
+		require.NoError(t, conn.WriteMessage(websocket.TextMessage, []byte(expected.Command)))
+		msg = ControlMessage{}
+		require.NoError(t, conn.ReadJSON(&msg))
+		assert.Equal(t, expected, msg)
+	}
+
+	require.NoError(t, conn.WriteMessage(websocket.TextMessage, []byte("exit")))
+	_, _, err := conn.ReadMessage()
+	assert.True(t, websocket.IsCloseError(err, websocket.CloseNormalClosure), err)
+}
+
+func TestServer_ControlInvalidStart(t *testing.T) {
+	ts := httptest.NewServer(NewServer())
+	defer ts.Close()
+
+	for _, query := range []string{"x=a", "direction=Q", "optimizer=fast"} {
+		_, resp, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(ts.URL, "http")+"/api/v1/control?"+query, nil)
+		require.Error(t, err, query)
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode, query)
+		require.NoError(t, resp.Body.Close())
+	}
+}
+
+func TestServer_ControlShutdown(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- NewServer().Serve(ctx, ln)
+	}()
+
+	conn := dialControl(t, "ws://"+ln.Addr().String()+"/api/v1/control")
+	var msg ControlMessage
+	require.NoError(t, conn.ReadJSON(&msg))
+
+	cancel()
+	require.NoError(t, <-done)
+	_, _, err = conn.ReadMessage()
+	assert.True(t, websocket.IsCloseError(err, websocket.CloseGoingAway), err)
+}
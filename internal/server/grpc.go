@@ -0,0 +1,273 @@
+package server
+
+//go:generate protoc -I roverpb --go_out=roverpb --go_opt=paths=source_relative --go-grpc_out=roverpb --go-grpc_opt=paths=source_relative roverpb/rover.proto
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mars-rover/internal/api"
+	"mars-rover/internal/models"
+	"mars-rover/internal/planner"
+	"mars-rover/internal/server/roverpb"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// directions соответствие направлений марсохода и protobuf
+var directions = map[models.Direction]roverpb.Direction{
+	models.North: roverpb.Direction_DIRECTION_NORTH,
+	models.East:  roverpb.Direction_DIRECTION_EAST,
+	models.South: roverpb.Direction_DIRECTION_SOUTH,
+	models.West:  roverpb.Direction_DIRECTION_WEST,
+}
+
+// GRPCServer gRPC-сервис марсохода с теми же настройками плато и оптимизации, что и HTTP API
+type GRPCServer struct {
+	roverpb.UnimplementedRoverServiceServer
+
+	server *Server
+	// stopping закрывается при остановке сервера, по нему завершаются потоки Drive,
+	// которых GracefulStop ждал бы до отключения клиента
+	stopping chan struct{}
+	stopOnce sync.Once
+}
+
+func NewGRPCServer(opts ...Option) *GRPCServer {
+	return &GRPCServer{server: NewServer(opts...), stopping: make(chan struct{})}
+}
+
+// Register регистрирует сервис на gRPC-сервере
+func (g *GRPCServer) Register(s grpc.ServiceRegistrar) {
+	roverpb.RegisterRoverServiceServer(s, g)
+}
+
+// ListenAndServe слушает addr и обслуживает запросы, пока не отменён ctx
+func (g *GRPCServer) ListenAndServe(ctx context.Context, addr string) error {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	return g.Serve(ctx, ln)
+}
+
+// Serve обслуживает запросы из ln, пока не отменён ctx. После отмены потоки Drive завершаются
+// с кодом Unavailable, а разовые запросы дорабатывают не дольше shutdownTimeout
+func (g *GRPCServer) Serve(ctx context.Context, ln net.Listener) error {
+	srv := grpc.NewServer()
+	g.Register(srv)
+	errs := make(chan error, 1)
+	go func() {
+		errs <- srv.Serve(ln)
+	}()
+
+	select {
+	case err := <-errs:
+		return err
+	case <-ctx.Done():
+	}
+
+	g.stopOnce.Do(func() { close(g.stopping) })
+	stopped := make(chan struct{})
+	go func() {
+		srv.GracefulStop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-time.After(shutdownTimeout):
+		srv.Stop()
+	}
+	return <-errs
+}
+
+// Calculate рассчитывает маршрут новым марсоходом. Недопустимые символы и синтаксические ошибки возвращаются
+// с кодом InvalidArgument, остановка марсохода на середине маршрута - в поле error ответа
+func (g *GRPCServer) Calculate(ctx context.Context, req *roverpb.CalculateRequest) (*roverpb.CalculateResponse, error) {
+	a, _, e := g.server.newApp(fromState(req.GetStart()), req.GetOptimizer())
+	if e != nil {
+		return nil, statusError(codes.InvalidArgument, e)
+	}
+	code, resp := run(a, req.GetCommands())
+	if resp.Position == nil {
+		if code == http.StatusUnprocessableEntity {
+			return nil, statusError(codes.InvalidArgument, resp.Error)
+		}
+		return nil, statusError(codes.Internal, resp.Error)
+	}
+
+	out := &roverpb.CalculateResponse{
+		State: toState(models.Coordinates{X: resp.Position.X, Y: resp.Position.Y}, models.Direction(resp.Direction)),
+	}
+	for _, m := range resp.Moves {
+		move := &roverpb.Move{Type: roverpb.MoveType_MOVE_TYPE_MOVEMENT, Value: int64(m.Value)}
+		if m.Type == models.Rotation {
+			move.Type = roverpb.MoveType_MOVE_TYPE_ROTATION
+		}
+		if m.Span != nil {
+			move.Span = &roverpb.Span{Start: int64(m.Span.Start), End: int64(m.Span.End)}
+		}
+		out.Moves = append(out.Moves, move)
+	}
+	if resp.Error != nil {
+		out.Error = &roverpb.RouteError{Code: resp.Error.Code, Message: resp.Error.Message, Detail: resp.Error.Detail}
+		if resp.Error.Index != nil {
+			out.Error.Index = int64(*resp.Error.Index)
+		}
+		if resp.Error.Span != nil {
+			out.Error.Span = &roverpb.Span{Start: int64(resp.Error.Span.Start), End: int64(resp.Error.Span.End)}
+		}
+	}
+	return out, nil
+}
+
+// Plan ищет кратчайший маршрут до клетки на плато сервера
+func (g *GRPCServer) Plan(ctx context.Context, req *roverpb.PlanRequest) (*roverpb.PlanResponse, error) {
+	start := planner.State{Pos: models.Coordinates{X: 1, Y: 1}, Direction: models.North}
+	if s := fromState(req.GetStart()); s != nil {
+		start.Pos = models.Coordinates{X: s.X, Y: s.Y}
+		if s.Direction != "" {
+			dir, err := models.ParseDirection(s.Direction)
+			if err != nil {
+				return nil, status.Errorf(codes.InvalidArgument, "%s: %s", api.CodeInvalidStart, err)
+			}
+			start.Direction = dir
+		}
+	}
+	var heading models.Direction
+	if req.GetHeading() != roverpb.Direction_DIRECTION_UNSPECIFIED {
+		dir, err := models.ParseDirection(string(fromDirection(req.GetHeading())))
+		if err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "%s: %s", api.CodeInvalidRequest, err)
+		}
+		heading = dir
+	}
+	if p := g.server.Plateau; p != nil && (!p.Contains(start.Pos) || p.Blocked(start.Pos)) {
+		return nil, status.Errorf(codes.InvalidArgument, "%s: start (%d, %d) is outside the plateau or blocked",
+			api.CodeInvalidStart, start.Pos.X, start.Pos.Y)
+	}
+
+	target := models.Coordinates{X: int(req.GetTarget().GetX()), Y: int(req.GetTarget().GetY())}
+	commands, err := planner.Plan(g.server.Plateau, start, target, heading)
+	switch {
+	case errors.Is(err, planner.ErrUnreachable):
+		return nil, status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, planner.ErrSearchLimit):
+		return nil, status.Error(codes.ResourceExhausted, err.Error())
+	case err != nil:
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return &roverpb.PlanResponse{Commands: commands}, nil
+}
+
+// Drive управляет марсоходом командами app.App.InteractiveControl. Марсоход создаётся по первому сообщению
+// с его start и optimizer. Сообщение без команды возвращает текущее состояние, exit завершает поток
+func (g *GRPCServer) Drive(stream roverpb.RoverService_DriveServer) error {
+	requests := make(chan *roverpb.DriveRequest)
+	errs := make(chan error, 1)
+	go func() {
+		for {
+			req, err := stream.Recv()
+			if err != nil {
+				errs <- err
+				return
+			}
+			select {
+			case requests <- req:
+			case <-stream.Context().Done():
+				return
+			}
+		}
+	}()
+
+	var d *driver
+	defer func() {
+		if d != nil {
+			d.close()
+		}
+	}()
+	for {
+		var req *roverpb.DriveRequest
+		select {
+		case req = <-requests:
+		case err := <-errs:
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		case <-g.stopping:
+			return status.Error(codes.Unavailable, "server is shutting down")
+		}
+
+		if d == nil {
+			var e *api.Error
+			d, e = g.server.newDriver(fromState(req.GetStart()), req.GetOptimizer())
+			if e != nil {
+				return statusError(codes.InvalidArgument, e)
+			}
+		}
+
+		msg := d.state("", fmt.Sprintf("Текущие координаты: (%d, %d), направление: %s",
+			d.rover.Pos.X, d.rover.Pos.Y, d.rover.Direction))
+		if req.GetCommand() != "" {
+			var ok bool
+			msg, ok = d.do(req.GetCommand())
+			if !ok {
+				return nil
+			}
+		}
+		err := stream.Send(&roverpb.DriveResponse{
+			Command: msg.Command,
+			Message: msg.Message,
+			State:   toState(models.Coordinates{X: msg.Position.X, Y: msg.Position.Y}, models.Direction(msg.Direction)),
+			Error:   msg.Error,
+		})
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// fromState переводит начальное состояние из protobuf, nil если состояние не задано
+func fromState(s *roverpb.State) *Start {
+	if s == nil {
+		return nil
+	}
+	start := &Start{X: int(s.GetPosition().GetX()), Y: int(s.GetPosition().GetY())}
+	if s.GetDirection() != roverpb.Direction_DIRECTION_UNSPECIFIED {
+		start.Direction = string(fromDirection(s.GetDirection()))
+	}
+	return start
+}
+
+func fromDirection(d roverpb.Direction) models.Direction {
+	for dir, pb := range directions {
+		if pb == d {
+			return dir
+		}
+	}
+	// неизвестное значение не пройдёт models.ParseDirection
+	return models.Direction(d.String())
+}
+
+func toState(pos models.Coordinates, dir models.Direction) *roverpb.State {
+	return &roverpb.State{
+		Position:  &roverpb.Position{X: int64(pos.X), Y: int64(pos.Y)},
+		Direction: directions[dir],
+	}
+}
+
+// statusError переводит ошибку API в статус gRPC: текст ошибки движка, если он есть, иначе сообщение
+func statusError(c codes.Code, e *api.Error) error {
+	if e.Detail != "" {
+		return status.Errorf(c, "%s: %s", e.Code, e.Detail)
+	}
+	return status.Errorf(c, "%s: %s", e.Code, e.Message)
+}
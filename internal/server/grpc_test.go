@@ -0,0 +1,243 @@
+package server
+
+import (
+	"context"
+	"io"
+	"mars-rover/internal/api"
+	"mars-rover/internal/models"
+	"mars-rover/internal/plateau"
+	"mars-rover/internal/server/roverpb"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/proto"
+)
+
+// startGRPC запускает сервис на bufconn и возвращает клиента, отмена контекста останавливает сервер
+func startGRPC(t *testing.T, opts ...Option) (roverpb.RoverServiceClient, context.CancelFunc, <-chan error) {
+	t.Helper()
+	ln := bufconn.Listen(1 << 20)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- NewGRPCServer(opts...).Serve(ctx, ln)
+	}()
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return ln.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = conn.Close()
+		cancel()
+	})
+	return roverpb.NewRoverServiceClient(conn), cancel, done
+}
+
+func pbState(x, y int64, dir roverpb.Direction) *roverpb.State {
+	return &roverpb.State{Position: &roverpb.Position{X: x, Y: y}, Direction: dir}
+}
+
+func TestGRPCServer_Calculate(t *testing.T) {
+	p := &plateau.Plateau{
+		Width: 5, Height: 5, Edge: plateau.EdgeReject,
+		Obstacles: plateau.NewObstacles(models.Coordinates{X: 0, Y: 4}),
+	}
+	client, _, _ := startGRPC(t, WithPlateau(p))
+
+	tests := []struct {
+		name     string
+		req      *roverpb.CalculateRequest
+		expected *roverpb.CalculateResponse
+		// errCode код ошибки марсохода в ответе, сама ошибка сравнивается отдельно
+		errCode string
+		code    codes.Code
+	}{
+		{
+			name: "маршрут выполнен",
+			req:  &roverpb.CalculateRequest{Commands: "FFRFF"},
+			expected: &roverpb.CalculateResponse{
+				State: pbState(3, 3, roverpb.Direction_DIRECTION_EAST),
+				Moves: []*roverpb.Move{
+					{Type: roverpb.MoveType_MOVE_TYPE_MOVEMENT, Value: 2, Span: &roverpb.Span{Start: 0, End: 2}},
+					{Type: roverpb.MoveType_MOVE_TYPE_ROTATION, Value: -1, Span: &roverpb.Span{Start: 2, End: 3}},
+					{Type: roverpb.MoveType_MOVE_TYPE_MOVEMENT, Value: 2, Span: &roverpb.Span{Start: 3, End: 5}},
+				},
+			},
+		},
+		{
+			name: "препятствие",
+			req:  &roverpb.CalculateRequest{Commands: "LFRFFF", Start: pbState(1, 1, roverpb.Direction_DIRECTION_NORTH)},
+			expected: &roverpb.CalculateResponse{
+				State: pbState(0, 3, roverpb.Direction_DIRECTION_NORTH),
+				Moves: []*roverpb.Move{
+					{Type: roverpb.MoveType_MOVE_TYPE_ROTATION, Value: 1, Span: &roverpb.Span{Start: 0, End: 1}},
+					{Type: roverpb.MoveType_MOVE_TYPE_MOVEMENT, Value: 1, Span: &roverpb.Span{Start: 1, End: 2}},
+					{Type: roverpb.MoveType_MOVE_TYPE_ROTATION, Value: -1, Span: &roverpb.Span{Start: 2, End: 3}},
+					{Type: roverpb.MoveType_MOVE_TYPE_MOVEMENT, Value: 3, Span: &roverpb.Span{Start: 3, End: 6}},
+				},
+			},
+			errCode: api.CodeObstacle,
+		},
+		{name: "недопустимый символ", req: &roverpb.CalculateRequest{Commands: "FXF"}, code: codes.InvalidArgument},
+		{name: "неизвестный оптимизатор", req: &roverpb.CalculateRequest{Optimizer: "fast"}, code: codes.InvalidArgument},
+		{
+			name: "старт за пределами плато",
+			req:  &roverpb.CalculateRequest{Start: pbState(7, 1, roverpb.Direction_DIRECTION_NORTH)},
+			code: codes.InvalidArgument,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := client.Calculate(context.Background(), tt.req)
+			if tt.code != codes.OK {
+				assert.Equal(t, tt.code, status.Code(err), err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.errCode, resp.GetError().GetCode())
+			resp.Error = nil
+			assert.True(t, proto.Equal(tt.expected, resp), "%v", resp)
+		})
+	}
+}
+
+func TestGRPCServer_Plan(t *testing.T) {
+	p := &plateau.Plateau{
+		Width: 5, Height: 5, Edge: plateau.EdgeReject,
+		Obstacles: plateau.NewObstacles(models.Coordinates{X: 1, Y: 2}),
+	}
+	client, _, _ := startGRPC(t, WithPlateau(p))
+
+	tests := []struct {
+		name string
+		req  *roverpb.PlanRequest
+		code codes.Code
+	}{
+		{name: "обход препятствия", req: &roverpb.PlanRequest{Target: &roverpb.Position{X: 1, Y: 3}}},
+		{
+			name: "направление в цели",
+			req: &roverpb.PlanRequest{
+				Start:   pbState(0, 0, roverpb.Direction_DIRECTION_EAST),
+				Target:  &roverpb.Position{X: 4, Y: 0},
+				Heading: roverpb.Direction_DIRECTION_SOUTH,
+			},
+		},
+		{name: "цель на препятствии", req: &roverpb.PlanRequest{Target: &roverpb.Position{X: 1, Y: 2}}, code: codes.NotFound},
+		{
+			name: "старт за пределами плато",
+			req:  &roverpb.PlanRequest{Start: pbState(9, 9, roverpb.Direction_DIRECTION_NORTH), Target: &roverpb.Position{}},
+			code: codes.InvalidArgument,
+		},
+		{
+			name: "неизвестное направление",
+			req:  &roverpb.PlanRequest{Target: &roverpb.Position{}, Heading: roverpb.Direction(42)},
+			code: codes.InvalidArgument,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := client.Plan(context.Background(), tt.req)
+			if tt.code != codes.OK {
+				assert.Equal(t, tt.code, status.Code(err), err)
+				return
+			}
+			require.NoError(t, err)
+
+			// план проигрывается через Calculate и должен привести марсоход в цель
+			route, err := client.Calculate(context.Background(), &roverpb.CalculateRequest{
+				Commands: resp.Commands, Start: tt.req.Start, Optimizer: "none",
+			})
+			require.NoError(t, err)
+			assert.Nil(t, route.Error)
+			assert.Equal(t, tt.req.Target.X, route.State.Position.X)
+			assert.Equal(t, tt.req.Target.Y, route.State.Position.Y)
+			if tt.req.Heading != roverpb.Direction_DIRECTION_UNSPECIFIED {
+				assert.Equal(t, tt.req.Heading, route.State.Direction)
+			}
+		})
+	}
+}
+
+func TestGRPCServer_Drive(t *testing.T) {
+	p := &plateau.Plateau{Obstacles: plateau.NewObstacles(models.Coordinates{X: 0, Y: 2})}
+	client, _, _ := startGRPC(t, WithPlateau(p))
+
+	stream, err := client.Drive(context.Background())
+	require.NoError(t, err)
+
+	require.NoError(t, stream.Send(&roverpb.DriveRequest{Start: pbState(0, 0, roverpb.Direction_DIRECTION_NORTH)}))
+	resp, err := stream.Recv()
+	require.NoError(t, err)
+	assert.True(t, proto.Equal(&roverpb.DriveResponse{
+		Message: "Текущие координаты: (0, 0), направление: N",
+		State:   pbState(0, 0, roverpb.Direction_DIRECTION_NORTH),
+	}, resp), "%v", resp)
+
+	steps := []*roverpb.DriveResponse{
+		{Command: "up", Message: "Текущие координаты: (0, 1), направление: N", State: pbState(0, 1, roverpb.Direction_DIRECTION_NORTH)},
+		{
+			Command: "up",
+			Message: "Марсоход остановился перед препятствием на команде с индексом 1. " +
+				"Последняя безопасная точка (0, 1), направление: N",
+			State: pbState(0, 1, roverpb.Direction_DIRECTION_NORTH),
+			Error: api.CodeObstacle,
+		},
+		{Command: "right", Message: "Текущие координаты: (0, 1), направление: E", State: pbState(0, 1, roverpb.Direction_DIRECTION_EAST)},
+		{
+			Command: "undo",
+			Message: "Команда отменена. Текущие координаты: (0, 1), направление: N",
+			State:   pbState(0, 1, roverpb.Direction_DIRECTION_NORTH),
+		},
+	}
+	for _, expected := range steps {
+		require.NoError(t, stream.Send(&roverpb.DriveRequest{Command: expected.Command}))
+		resp, err := stream.Recv()
+		require.NoError(t, err)
+		assert.True(t, proto.Equal(expected, resp), "%s: %v", expected.Command, resp)
+	}
+
+	require.NoError(t, stream.Send(&roverpb.DriveRequest{Command: "exit"}))
+	_, err = stream.Recv()
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestGRPCServer_DriveInvalidStart(t *testing.T) {
+	client, _, _ := startGRPC(t)
+
+	for _, req := range []*roverpb.DriveRequest{
+		{Start: pbState(1, 1, roverpb.Direction(42))},
+		{Optimizer: "fast"},
+	} {
+		stream, err := client.Drive(context.Background())
+		require.NoError(t, err)
+		require.NoError(t, stream.Send(req))
+		_, err = stream.Recv()
+		assert.Equal(t, codes.InvalidArgument, status.Code(err), err)
+	}
+}
+
+func TestGRPCServer_Shutdown(t *testing.T) {
+	client, cancel, done := startGRPC(t)
+
+	stream, err := client.Drive(context.Background())
+	require.NoError(t, err)
+	require.NoError(t, stream.Send(&roverpb.DriveRequest{}))
+	_, err = stream.Recv()
+	require.NoError(t, err)
+
+	cancel()
+	_, err = stream.Recv()
+	assert.Equal(t, codes.Unavailable, status.Code(err), err)
+	require.NoError(t, <-done)
+}
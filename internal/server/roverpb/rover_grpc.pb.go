@@ -0,0 +1,200 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: rover.proto
+
+// Сервис расчёта маршрутов и управления марсоходом
+
+package roverpb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	RoverService_Calculate_FullMethodName = "/rover.v1.RoverService/Calculate"
+	RoverService_Plan_FullMethodName      = "/rover.v1.RoverService/Plan"
+	RoverService_Drive_FullMethodName     = "/rover.v1.RoverService/Drive"
+)
+
+// RoverServiceClient is the client API for RoverService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type RoverServiceClient interface {
+	// Calculate рассчитывает маршрут новым марсоходом, как POST /api/v1/routes
+	Calculate(ctx context.Context, in *CalculateRequest, opts ...grpc.CallOption) (*CalculateResponse, error)
+	// Plan ищет кратчайший маршрут до клетки, как команда rover plan
+	Plan(ctx context.Context, in *PlanRequest, opts ...grpc.CallOption) (*PlanResponse, error)
+	// Drive управляет марсоходом командами интерактивного режима: каждая команда клиента получает один ответ
+	Drive(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[DriveRequest, DriveResponse], error)
+}
+
+type roverServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewRoverServiceClient(cc grpc.ClientConnInterface) RoverServiceClient {
+	return &roverServiceClient{cc}
+}
+
+func (c *roverServiceClient) Calculate(ctx context.Context, in *CalculateRequest, opts ...grpc.CallOption) (*CalculateResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CalculateResponse)
+	err := c.cc.Invoke(ctx, RoverService_Calculate_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *roverServiceClient) Plan(ctx context.Context, in *PlanRequest, opts ...grpc.CallOption) (*PlanResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(PlanResponse)
+	err := c.cc.Invoke(ctx, RoverService_Plan_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *roverServiceClient) Drive(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[DriveRequest, DriveResponse], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &RoverService_ServiceDesc.Streams[0], RoverService_Drive_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[DriveRequest, DriveResponse]{ClientStream: stream}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type RoverService_DriveClient = grpc.BidiStreamingClient[DriveRequest, DriveResponse]
+
+// RoverServiceServer is the server API for RoverService service.
+// All implementations must embed UnimplementedRoverServiceServer
+// for forward compatibility.
+type RoverServiceServer interface {
+	// Calculate рассчитывает маршрут новым марсоходом, как POST /api/v1/routes
+	Calculate(context.Context, *CalculateRequest) (*CalculateResponse, error)
+	// Plan ищет кратчайший маршрут до клетки, как команда rover plan
+	Plan(context.Context, *PlanRequest) (*PlanResponse, error)
+	// Drive управляет марсоходом командами интерактивного режима: каждая команда клиента получает один ответ
+	Drive(grpc.BidiStreamingServer[DriveRequest, DriveResponse]) error
+	mustEmbedUnimplementedRoverServiceServer()
+}
+
+// UnimplementedRoverServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedRoverServiceServer struct{}
+
+func (UnimplementedRoverServiceServer) Calculate(context.Context, *CalculateRequest) (*CalculateResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Calculate not implemented")
+}
+func (UnimplementedRoverServiceServer) Plan(context.Context, *PlanRequest) (*PlanResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Plan not implemented")
+}
+func (UnimplementedRoverServiceServer) Drive(grpc.BidiStreamingServer[DriveRequest, DriveResponse]) error {
+	return status.Errorf(codes.Unimplemented, "method Drive not implemented")
+}
+func (UnimplementedRoverServiceServer) mustEmbedUnimplementedRoverServiceServer() {}
+func (UnimplementedRoverServiceServer) testEmbeddedByValue()                      {}
+
+// UnsafeRoverServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to RoverServiceServer will
+// result in compilation errors.
+type UnsafeRoverServiceServer interface {
+	mustEmbedUnimplementedRoverServiceServer()
+}
+
+func RegisterRoverServiceServer(s grpc.ServiceRegistrar, srv RoverServiceServer) {
+	// If the following call pancis, it indicates UnimplementedRoverServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&RoverService_ServiceDesc, srv)
+}
+
+func _RoverService_Calculate_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CalculateRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RoverServiceServer).Calculate(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RoverService_Calculate_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RoverServiceServer).Calculate(ctx, req.(*CalculateRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RoverService_Plan_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PlanRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RoverServiceServer).Plan(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RoverService_Plan_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RoverServiceServer).Plan(ctx, req.(*PlanRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RoverService_Drive_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(RoverServiceServer).Drive(&grpc.GenericServerStream[DriveRequest, DriveResponse]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type RoverService_DriveServer = grpc.BidiStreamingServer[DriveRequest, DriveResponse]
+
+// RoverService_ServiceDesc is the grpc.ServiceDesc for RoverService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var RoverService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "rover.v1.RoverService",
+	HandlerType: (*RoverServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Calculate",
+			Handler:    _RoverService_Calculate_Handler,
+		},
+		{
+			MethodName: "Plan",
+			Handler:    _RoverService_Plan_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Drive",
+			Handler:       _RoverService_Drive_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+	},
+	Metadata: "rover.proto",
+}
@@ -0,0 +1,299 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mars-rover/internal/api"
+	"mars-rover/internal/app"
+	"mars-rover/internal/models"
+	"mars-rover/internal/optimization"
+	"mars-rover/internal/plateau"
+	"mars-rover/internal/rover"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	// maxBodyBytes ограничение на размер тела запроса
+	maxBodyBytes = 1 << 20
+	// shutdownTimeout сколько Serve ждёт завершения запросов после отмены контекста
+	shutdownTimeout = 10 * time.Second
+)
+
+// Start начальное состояние марсохода в запросе, по умолчанию (1, 1) и север
+type Start struct {
+	X         int    `json:"x"`
+	Y         int    `json:"y"`
+	Direction string `json:"direction"`
+}
+
+// RouteRequest запрос на расчёт маршрута
+type RouteRequest struct {
+	Commands string `json:"commands"`
+	Start    *Start `json:"start,omitempty"`
+	// Optimizer стратегия оптимизации, по умолчанию стратегия сервера
+	Optimizer string `json:"optimizer,omitempty"`
+}
+
+// Strategy стратегия оптимизации в ответе API
+type Strategy struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Aliases     []string `json:"aliases,omitempty"`
+}
+
+// Server HTTP API для расчёта маршрутов. Разовый расчёт выполняет новый марсоход на общем плато,
+// поэтому такие запросы не влияют друг на друга. Сессии марсоходов хранят марсоход между запросами
+type Server struct {
+	Plateau  *plateau.Plateau
+	Strategy string
+	Registry *optimization.Registry
+	// IdleTimeout через сколько времени без запросов сессия марсохода удаляется
+	IdleTimeout time.Duration
+
+	mux      *http.ServeMux
+	sessions *sessions
+	// now текущее время, в тестах подменяется
+	now func() time.Time
+}
+
+type Option func(*Server)
+
+// WithPlateau задаёт плато, по которому ездят марсоходы, nil для бесконечной плоскости
+func WithPlateau(p *plateau.Plateau) Option {
+	return func(s *Server) {
+		s.Plateau = p
+	}
+}
+
+// WithStrategy задаёт стратегию оптимизации для запросов, в которых она не указана
+func WithStrategy(name string) Option {
+	return func(s *Server) {
+		s.Strategy = name
+	}
+}
+
+// WithRegistry задаёт реестр стратегий оптимизации вместо optimization.DefaultRegistry
+func WithRegistry(r *optimization.Registry) Option {
+	return func(s *Server) {
+		s.Registry = r
+	}
+}
+
+func NewServer(opts ...Option) *Server {
+	s := &Server{
+		Strategy:    optimization.DefaultStrategy,
+		Registry:    optimization.DefaultRegistry,
+		IdleTimeout: DefaultIdleTimeout,
+		mux:         http.NewServeMux(),
+		sessions:    newSessions(),
+		now:         time.Now,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if s.IdleTimeout <= 0 {
+		s.IdleTimeout = DefaultIdleTimeout
+	}
+
+	s.mux.HandleFunc("/api/v1/routes", s.handleRoutes)
+	s.mux.HandleFunc(roversPath, s.handleRovers)
+	s.mux.HandleFunc(roversPath+"/", s.handleRover)
+	s.mux.HandleFunc(controlPath, s.handleControl)
+	s.mux.HandleFunc("/api/v1/optimizers", s.handleOptimizers)
+	s.mux.HandleFunc("/healthz", s.handleHealth)
+	return s
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// ListenAndServe слушает addr и обслуживает запросы, пока не отменён ctx
+func (s *Server) ListenAndServe(ctx context.Context, addr string) error {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	return s.Serve(ctx, ln)
+}
+
+// Serve обслуживает запросы из ln, пока не отменён ctx. После отмены новые соединения не принимаются,
+// а начатые запросы дорабатывают не дольше shutdownTimeout. Соединения WebSocket получают кадр закрытия.
+// Пока сервер работает, истёкшие сессии марсоходов периодически удаляются
+func (s *Server) Serve(ctx context.Context, ln net.Listener) error {
+	srv := &http.Server{
+		Handler:           s,
+		ReadHeaderTimeout: 5 * time.Second,
+		// контекст запросов отменяется при остановке сервера, по нему закрываются соединения WebSocket,
+		// которые Shutdown не отслеживает
+		BaseContext: func(net.Listener) context.Context { return ctx },
+	}
+	errs := make(chan error, 1)
+	go func() {
+		errs <- srv.Serve(ln)
+	}()
+	go func() {
+		ticker := time.NewTicker(max(s.IdleTimeout/2, time.Second))
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				s.expire()
+			}
+		}
+	}()
+
+	select {
+	case err := <-errs:
+		return err
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		return fmt.Errorf("server shutdown: %w", err)
+	}
+	if err := <-errs; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+func (s *Server) handleRoutes(w http.ResponseWriter, r *http.Request) {
+	if !allow(w, r, http.MethodPost) {
+		return
+	}
+	var req RouteRequest
+	if !decode(w, r, &req) {
+		return
+	}
+
+	status, resp := s.calculate(req)
+	writeJSON(w, status, resp)
+}
+
+// calculate выполняет маршрут запроса и возвращает код ответа и тело
+func (s *Server) calculate(req RouteRequest) (int, *api.RouteResponse) {
+	a, _, e := s.newApp(req.Start, req.Optimizer)
+	if e != nil {
+		return http.StatusBadRequest, &api.RouteResponse{Error: e}
+	}
+	return run(a, req.Commands)
+}
+
+// newApp создаёт марсоход в начальном состоянии start с оптимизатором стратегии strategy.
+// Пустые start и strategy означают (1, 1), север и стратегию сервера
+func (s *Server) newApp(start *Start, strategy string) (*app.App, *rover.Rover, *api.Error) {
+	pos, dir := models.Coordinates{X: 1, Y: 1}, models.North
+	if start != nil {
+		pos = models.Coordinates{X: start.X, Y: start.Y}
+		if start.Direction != "" {
+			var err error
+			dir, err = models.ParseDirection(start.Direction)
+			if err != nil {
+				return nil, nil, &api.Error{
+					Code: api.CodeInvalidStart, Message: "Некорректное начальное направление", Detail: err.Error(),
+				}
+			}
+		}
+	}
+	if s.Plateau != nil && (!s.Plateau.Contains(pos) || s.Plateau.Blocked(pos)) {
+		return nil, nil, &api.Error{
+			Code:    api.CodeInvalidStart,
+			Message: fmt.Sprintf("Начальная клетка (%d, %d) за пределами плато или занята препятствием", pos.X, pos.Y),
+		}
+	}
+
+	if strategy == "" {
+		strategy = s.Strategy
+	}
+	optimizer, err := s.Registry.New(strategy, optimization.WithPlateau(s.Plateau), optimization.WithStart(pos, dir))
+	if err != nil {
+		return nil, nil, &api.Error{
+			Code: api.CodeUnknownOptimizer, Message: "Неизвестная стратегия оптимизации", Detail: err.Error(),
+		}
+	}
+
+	r := rover.NewRover(rover.WithPosition(pos), rover.WithDirection(dir))
+	return app.NewApp(r, optimizer, app.WithPlateau(s.Plateau)), r, nil
+}
+
+// run выполняет команды марсоходом a и возвращает код ответа и тело
+func run(a *app.App, commands string) (int, *api.RouteResponse) {
+	position, direction, err := a.HandleCommands(commands)
+	resp := api.NewRouteResponse(position, direction, a.Route(), a.Spans(), err)
+	if resp.Position == nil {
+		return errorStatus(err), resp
+	}
+	return http.StatusOK, resp
+}
+
+func (s *Server) handleOptimizers(w http.ResponseWriter, r *http.Request) {
+	if !allow(w, r, http.MethodGet) {
+		return
+	}
+
+	strategies := []Strategy{}
+	for _, st := range s.Registry.Strategies() {
+		strategies = append(strategies, Strategy{Name: st.Name, Description: st.Description, Aliases: st.Aliases})
+	}
+	writeJSON(w, http.StatusOK, strategies)
+}
+
+func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// errorStatus код ответа для ошибки, после которой маршрут не выполнялся
+func errorStatus(err error) int {
+	if errors.Is(err, models.ErrIncorrectSymbol) || errors.Is(err, models.ErrRouteSyntax) ||
+		errors.Is(err, models.ErrRouteTooLong) {
+		return http.StatusUnprocessableEntity
+	}
+	return http.StatusInternalServerError
+}
+
+// allow проверяет метод запроса и отвечает 405, если метод не из methods
+func allow(w http.ResponseWriter, r *http.Request, methods ...string) bool {
+	for _, method := range methods {
+		if r.Method == method {
+			return true
+		}
+	}
+	w.Header().Set("Allow", strings.Join(methods, ", "))
+	writeError(w, http.StatusMethodNotAllowed,
+		&api.Error{Code: api.CodeInvalidRequest, Message: "Метод не поддерживается"})
+	return false
+}
+
+// decode читает JSON из тела запроса в v и отвечает 400, если тело некорректно. Пустое тело оставляет v пустым
+func decode(w http.ResponseWriter, r *http.Request, v any) bool {
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBodyBytes))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(v); err != nil && !errors.Is(err, io.EOF) {
+		writeError(w, http.StatusBadRequest, &api.Error{
+			Code: api.CodeInvalidRequest, Message: "Некорректное тело запроса", Detail: err.Error(),
+		})
+		return false
+	}
+	return true
+}
+
+func writeError(w http.ResponseWriter, status int, e *api.Error) {
+	writeJSON(w, status, &api.RouteResponse{Error: e})
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,222 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"mars-rover/internal/api"
+	"mars-rover/internal/models"
+	"mars-rover/internal/plateau"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func intPtr(v int) *int {
+	return &v
+}
+
+func TestServer_Routes(t *testing.T) {
+	p := &plateau.Plateau{Width: 5, Height: 5, Edge: plateau.EdgeReject,
+		Obstacles: plateau.NewObstacles(models.Coordinates{X: 0, Y: 4})}
+	s := NewServer(WithPlateau(p))
+
+	tests := []struct {
+		name     string
+		body     string
+		status   int
+		expected api.RouteResponse
+	}{
+		{
+			name:   "Route with default start",
+			body:   `{"commands": "FFRFF"}`,
+			status: http.StatusOK,
+			expected: api.RouteResponse{
+				Position:  &api.Position{X: 3, Y: 3},
+				Direction: "E",
+			},
+		},
+		{
+			name:   "Route with start and optimizer",
+			body:   `{"commands": "FFLFF", "start": {"x": 0, "y": 0, "direction": "E"}, "optimizer": "passthrough"}`,
+			status: http.StatusOK,
+			expected: api.RouteResponse{
+				Position:  &api.Position{X: 2, Y: 2},
+				Direction: "N",
+				Moves: []api.Move{
+					{Type: models.Movement, Value: 1, Span: &api.Span{Start: 0, End: 1}},
+					{Type: models.Movement, Value: 1, Span: &api.Span{Start: 1, End: 2}},
+					{Type: models.Rotation, Value: 1, Span: &api.Span{Start: 2, End: 3}},
+					{Type: models.Movement, Value: 1, Span: &api.Span{Start: 3, End: 4}},
+					{Type: models.Movement, Value: 1, Span: &api.Span{Start: 4, End: 5}},
+				},
+			},
+		},
+		{
+			// в режиме reject движение за край плато не выполняется целиком
+			name:   "Rover stops at the edge",
+			body:   `{"commands": "FFFFF"}`,
+			status: http.StatusOK,
+			expected: api.RouteResponse{
+				Position:  &api.Position{X: 1, Y: 4},
+				Direction: "N",
+				Moves:     []api.Move{{Type: models.Movement, Value: 5, Span: &api.Span{Start: 0, End: 5}}},
+				Error: &api.Error{
+					Code: api.CodeOutOfBounds,
+					Message: "Марсоход упёрся в границу плато на команде с индексом 3 (исходные команды [0, 5)). " +
+						"Марсоход остановился в точке (1, 4), направление: N",
+					Detail: "route stopped at command 3, position (1, 4) N: boundary error: move leaves the plateau: (1, 6)",
+					Index:  intPtr(3),
+					Span:   &api.Span{Start: 0, End: 5},
+				},
+			},
+		},
+		{
+			name:   "Invalid symbols",
+			body:   `{"commands": "FXFY"}`,
+			status: http.StatusUnprocessableEntity,
+			expected: api.RouteResponse{
+				Error: &api.Error{
+					Code: api.CodeInvalidSymbol,
+					Symbols: []api.Symbol{
+						{Symbol: "X", Offset: 1},
+						{Symbol: "Y", Offset: 3},
+					},
+				},
+			},
+		},
+		{
+			name:     "Syntax error",
+			body:     `{"commands": "2(FF"}`,
+			status:   http.StatusUnprocessableEntity,
+			expected: api.RouteResponse{Error: &api.Error{Code: api.CodeSyntax}},
+		},
+		{
+			name:     "Huge mixed group",
+			body:     `{"commands": "1000000000000(FR)"}`,
+			status:   http.StatusUnprocessableEntity,
+			expected: api.RouteResponse{Error: &api.Error{Code: api.CodeRouteTooLong}},
+		},
+		{
+			name:     "Unknown optimizer",
+			body:     `{"commands": "F", "optimizer": "fast"}`,
+			status:   http.StatusBadRequest,
+			expected: api.RouteResponse{Error: &api.Error{Code: api.CodeUnknownOptimizer}},
+		},
+		{
+			name:     "Invalid direction",
+			body:     `{"commands": "F", "start": {"x": 1, "y": 1, "direction": "Q"}}`,
+			status:   http.StatusBadRequest,
+			expected: api.RouteResponse{Error: &api.Error{Code: api.CodeInvalidStart}},
+		},
+		{
+			name:     "Start on obstacle",
+			body:     `{"commands": "F", "start": {"x": 0, "y": 4}}`,
+			status:   http.StatusBadRequest,
+			expected: api.RouteResponse{Error: &api.Error{Code: api.CodeInvalidStart}},
+		},
+		{
+			name:     "Malformed body",
+			body:     `{"commands": 5}`,
+			status:   http.StatusBadRequest,
+			expected: api.RouteResponse{Error: &api.Error{Code: api.CodeInvalidRequest}},
+		},
+		{
+			name:     "Unknown field",
+			body:     `{"route": "F"}`,
+			status:   http.StatusBadRequest,
+			expected: api.RouteResponse{Error: &api.Error{Code: api.CodeInvalidRequest}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/routes", strings.NewReader(tt.body))
+			rec := httptest.NewRecorder()
+			s.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.status, rec.Code)
+			assert.Equal(t, "application/json; charset=utf-8", rec.Header().Get("Content-Type"))
+
+			var resp api.RouteResponse
+			require.NoError(t, json.NewDecoder(rec.Body).Decode(&resp))
+			assert.Equal(t, tt.expected.Position, resp.Position)
+			assert.Equal(t, tt.expected.Direction, resp.Direction)
+			if tt.expected.Moves != nil {
+				assert.Equal(t, tt.expected.Moves, resp.Moves)
+			}
+			if tt.expected.Error == nil {
+				assert.Nil(t, resp.Error)
+				return
+			}
+			require.NotNil(t, resp.Error)
+			assert.Equal(t, tt.expected.Error.Code, resp.Error.Code)
+			assert.NotEmpty(t, resp.Error.Message)
+			if tt.expected.Error.Message != "" {
+				assert.Equal(t, tt.expected.Error, resp.Error)
+			}
+			if tt.expected.Error.Symbols != nil {
+				assert.Equal(t, tt.expected.Error.Symbols, resp.Error.Symbols)
+			}
+		})
+	}
+}
+
+func TestServer_MethodNotAllowed(t *testing.T) {
+	s := NewServer()
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/routes", nil)
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+	assert.Equal(t, http.MethodPost, rec.Header().Get("Allow"))
+}
+
+func TestServer_Optimizers(t *testing.T) {
+	s := NewServer()
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/optimizers", nil)
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	var strategies []Strategy
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&strategies))
+	var names []string
+	for _, st := range strategies {
+		names = append(names, st.Name)
+	}
+	assert.Equal(t, []string{"minimal", "obstacle-aware", "passthrough", "run-length"}, names)
+}
+
+func TestServer_GracefulShutdown(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- NewServer().Serve(ctx, ln)
+	}()
+
+	resp, err := http.Post("http://"+ln.Addr().String()+"/api/v1/routes", "application/json",
+		strings.NewReader(`{"commands": "FFL"}`))
+	require.NoError(t, err)
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Contains(t, string(body), `"position":{"x":1,"y":3},"direction":"W"`)
+
+	cancel()
+	require.NoError(t, <-done)
+
+	_, err = http.Get("http://" + ln.Addr().String() + "/healthz")
+	assert.Error(t, err)
+}
@@ -0,0 +1,275 @@
+package server
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"mars-rover/internal/api"
+	"mars-rover/internal/app"
+	"mars-rover/internal/rover"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultIdleTimeout через сколько времени без запросов сессия марсохода удаляется
+	DefaultIdleTimeout = 30 * time.Minute
+	// maxSessions ограничение на количество одновременных сессий
+	maxSessions = 10000
+	// roversPath путь API сессий марсоходов
+	roversPath = "/api/v1/rovers"
+)
+
+const (
+	CodeUnknownRover  = "unknown_rover"
+	CodeTooManyRovers = "too_many_rovers"
+)
+
+// CreateRoverRequest запрос на создание сессии марсохода
+type CreateRoverRequest struct {
+	Start     *Start `json:"start,omitempty"`
+	Optimizer string `json:"optimizer,omitempty"`
+}
+
+// CommandsRequest команды, которые марсоход сессии выполняет с текущего положения
+type CommandsRequest struct {
+	Commands string `json:"commands"`
+}
+
+// Odometer пробег марсохода сессии
+type Odometer struct {
+	Forward  int `json:"forward"`
+	Backward int `json:"backward"`
+	Turns    int `json:"turns"`
+}
+
+// RoverState состояние марсохода сессии
+type RoverState struct {
+	ID        string       `json:"id"`
+	Position  api.Position `json:"position"`
+	Direction string       `json:"direction"`
+	Optimizer string       `json:"optimizer"`
+	Odometer  Odometer     `json:"odometer"`
+	ExpiresAt time.Time    `json:"expires_at"`
+}
+
+// session марсоход, который живёт между запросами. mu защищает марсоход: Rover не синхронизирован,
+// а запросы к одной сессии могут прийти одновременно
+type session struct {
+	id       string
+	start    *Start
+	strategy string
+
+	mu    sync.Mutex
+	app   *app.App
+	rover *rover.Rover
+}
+
+// sessions сессии марсоходов по идентификатору. mu защищает byID и lastUsed
+type sessions struct {
+	mu       sync.Mutex
+	byID     map[string]*session
+	lastUsed map[string]time.Time
+}
+
+func newSessions() *sessions {
+	return &sessions{byID: map[string]*session{}, lastUsed: map[string]time.Time{}}
+}
+
+// WithIdleTimeout задаёт, через сколько времени без запросов сессия марсохода удаляется, 0 означает DefaultIdleTimeout
+func WithIdleTimeout(d time.Duration) Option {
+	return func(s *Server) {
+		s.IdleTimeout = d
+	}
+}
+
+// add сохраняет сессию, если сессий не больше maxSessions, и возвращает время её истечения
+func (s *Server) add(sess *session) (time.Time, bool) {
+	s.sessions.mu.Lock()
+	defer s.sessions.mu.Unlock()
+
+	s.expireLocked()
+	if len(s.sessions.byID) >= maxSessions {
+		return time.Time{}, false
+	}
+	now := s.now()
+	s.sessions.byID[sess.id] = sess
+	s.sessions.lastUsed[sess.id] = now
+	return now.Add(s.IdleTimeout), true
+}
+
+// session возвращает сессию id и продлевает её, false если сессии нет или она истекла
+func (s *Server) session(id string) (*session, time.Time, bool) {
+	s.sessions.mu.Lock()
+	defer s.sessions.mu.Unlock()
+
+	s.expireLocked()
+	sess, ok := s.sessions.byID[id]
+	if !ok {
+		return nil, time.Time{}, false
+	}
+	now := s.now()
+	s.sessions.lastUsed[id] = now
+	return sess, now.Add(s.IdleTimeout), true
+}
+
+func (s *Server) remove(id string) bool {
+	s.sessions.mu.Lock()
+	defer s.sessions.mu.Unlock()
+
+	_, ok := s.sessions.byID[id]
+	delete(s.sessions.byID, id)
+	delete(s.sessions.lastUsed, id)
+	return ok
+}
+
+// expire удаляет сессии, к которым не было запросов дольше IdleTimeout
+func (s *Server) expire() {
+	s.sessions.mu.Lock()
+	defer s.sessions.mu.Unlock()
+	s.expireLocked()
+}
+
+func (s *Server) expireLocked() {
+	deadline := s.now().Add(-s.IdleTimeout)
+	for id, used := range s.sessions.lastUsed {
+		if !used.After(deadline) {
+			delete(s.sessions.byID, id)
+			delete(s.sessions.lastUsed, id)
+		}
+	}
+}
+
+// handleRovers создаёт сессию марсохода: POST /api/v1/rovers
+func (s *Server) handleRovers(w http.ResponseWriter, r *http.Request) {
+	if !allow(w, r, http.MethodPost) {
+		return
+	}
+	var req CreateRoverRequest
+	if !decode(w, r, &req) {
+		return
+	}
+
+	a, rv, e := s.newApp(req.Start, req.Optimizer)
+	if e != nil {
+		writeError(w, http.StatusBadRequest, e)
+		return
+	}
+	strategy := req.Optimizer
+	if strategy == "" {
+		strategy = s.Strategy
+	}
+	id, err := newID()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, &api.Error{
+			Code: api.CodeInternal, Message: "Не удалось создать сессию", Detail: err.Error(),
+		})
+		return
+	}
+	sess := &session{id: id, start: req.Start, strategy: strategy, app: a, rover: rv}
+	expires, ok := s.add(sess)
+	if !ok {
+		writeError(w, http.StatusServiceUnavailable, &api.Error{
+			Code: CodeTooManyRovers, Message: "Слишком много сессий марсоходов",
+		})
+		return
+	}
+
+	w.Header().Set("Location", roversPath+"/"+id)
+	writeJSON(w, http.StatusCreated, sess.state(expires))
+}
+
+// handleRover обслуживает сессию марсохода:
+// GET /api/v1/rovers/{id} состояние, DELETE /api/v1/rovers/{id} удаление,
+// POST /api/v1/rovers/{id}/commands выполнение команд, POST /api/v1/rovers/{id}/reset возврат в начальное состояние
+func (s *Server) handleRover(w http.ResponseWriter, r *http.Request) {
+	id, action, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, roversPath+"/"), "/")
+
+	switch action {
+	case "":
+		if !allow(w, r, http.MethodGet, http.MethodDelete) {
+			return
+		}
+	case "commands", "reset":
+		if !allow(w, r, http.MethodPost) {
+			return
+		}
+	default:
+		http.NotFound(w, r)
+		return
+	}
+
+	if r.Method == http.MethodDelete {
+		if !s.remove(id) {
+			writeUnknownRover(w, id)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	sess, expires, ok := s.session(id)
+	if !ok {
+		writeUnknownRover(w, id)
+		return
+	}
+
+	switch action {
+	case "":
+		writeJSON(w, http.StatusOK, sess.state(expires))
+	case "commands":
+		var req CommandsRequest
+		if !decode(w, r, &req) {
+			return
+		}
+		sess.mu.Lock()
+		status, resp := run(sess.app, req.Commands)
+		sess.mu.Unlock()
+		writeJSON(w, status, resp)
+	case "reset":
+		// стратегия и начальное состояние уже проверены при создании сессии
+		a, rv, e := s.newApp(sess.start, sess.strategy)
+		if e != nil {
+			writeError(w, http.StatusInternalServerError, e)
+			return
+		}
+		sess.mu.Lock()
+		sess.app, sess.rover = a, rv
+		sess.mu.Unlock()
+		writeJSON(w, http.StatusOK, sess.state(expires))
+	}
+}
+
+func (sess *session) state(expires time.Time) RoverState {
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+
+	return RoverState{
+		ID:        sess.id,
+		Position:  api.Position{X: sess.rover.Pos.X, Y: sess.rover.Pos.Y},
+		Direction: string(sess.rover.Direction),
+		Optimizer: sess.strategy,
+		Odometer: Odometer{
+			Forward:  sess.rover.Odometer.Forward,
+			Backward: sess.rover.Odometer.Backward,
+			Turns:    sess.rover.Odometer.Turns,
+		},
+		ExpiresAt: expires,
+	}
+}
+
+func writeUnknownRover(w http.ResponseWriter, id string) {
+	writeError(w, http.StatusNotFound, &api.Error{
+		Code: CodeUnknownRover, Message: "Сессия марсохода не найдена или истекла", Detail: id,
+	})
+}
+
+// newID случайный идентификатор сессии
+func newID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,152 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"mars-rover/internal/api"
+	"mars-rover/internal/models"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// do выполняет запрос к серверу и разбирает JSON ответа в out, если out не nil
+func do(t *testing.T, s *Server, method, path, body string, out any) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+	if out != nil {
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), out), rec.Body.String())
+	}
+	return rec
+}
+
+func TestServer_RoverSession(t *testing.T) {
+	s := NewServer()
+
+	var created RoverState
+	rec := do(t, s, http.MethodPost, "/api/v1/rovers", `{"start": {"x": 0, "y": 0, "direction": "E"}}`, &created)
+	require.Equal(t, http.StatusCreated, rec.Code)
+	assert.Equal(t, "/api/v1/rovers/"+created.ID, rec.Header().Get("Location"))
+	assert.Equal(t, api.Position{X: 0, Y: 0}, created.Position)
+	assert.Equal(t, "E", created.Direction)
+	assert.Equal(t, "run-length", created.Optimizer)
+	path := "/api/v1/rovers/" + created.ID
+
+	// команды выполняются с того места, где марсоход остановился после предыдущих
+	var resp api.RouteResponse
+	rec = do(t, s, http.MethodPost, path+"/commands", `{"commands": "FF"}`, &resp)
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, &api.Position{X: 2, Y: 0}, resp.Position)
+	rec = do(t, s, http.MethodPost, path+"/commands", `{"commands": "LF"}`, &resp)
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, &api.Position{X: 2, Y: 1}, resp.Position)
+	assert.Equal(t, "N", resp.Direction)
+
+	var state RoverState
+	rec = do(t, s, http.MethodGet, path, "", &state)
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, api.Position{X: 2, Y: 1}, state.Position)
+	assert.Equal(t, Odometer{Forward: 3, Turns: 1}, state.Odometer)
+
+	rec = do(t, s, http.MethodPost, path+"/commands", `{"commands": "FX"}`, &resp)
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+	assert.Equal(t, api.CodeInvalidSymbol, resp.Error.Code)
+
+	rec = do(t, s, http.MethodPost, path+"/reset", "", &state)
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, api.Position{X: 0, Y: 0}, state.Position)
+	assert.Equal(t, "E", state.Direction)
+	assert.Equal(t, Odometer{}, state.Odometer)
+
+	rec = do(t, s, http.MethodDelete, path, "", nil)
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	rec = do(t, s, http.MethodGet, path, "", &resp)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	assert.Equal(t, CodeUnknownRover, resp.Error.Code)
+	rec = do(t, s, http.MethodDelete, path, "", nil)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestServer_RoverSessionErrors(t *testing.T) {
+	s := NewServer()
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+		status int
+	}{
+		{"Unknown optimizer", http.MethodPost, "/api/v1/rovers", `{"optimizer": "fast"}`, http.StatusBadRequest},
+		{"Invalid start", http.MethodPost, "/api/v1/rovers", `{"start": {"direction": "Q"}}`, http.StatusBadRequest},
+		{"List is not supported", http.MethodGet, "/api/v1/rovers", "", http.StatusMethodNotAllowed},
+		{"Unknown rover", http.MethodPost, "/api/v1/rovers/missing/commands", `{"commands": "F"}`, http.StatusNotFound},
+		{"Unknown action", http.MethodPost, "/api/v1/rovers/missing/jump", "", http.StatusNotFound},
+		{"Wrong method", http.MethodGet, "/api/v1/rovers/missing/reset", "", http.StatusMethodNotAllowed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := do(t, s, tt.method, tt.path, tt.body, nil)
+			assert.Equal(t, tt.status, rec.Code)
+		})
+	}
+}
+
+func TestServer_RoverSessionExpires(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	s := NewServer(WithIdleTimeout(time.Minute))
+	s.now = func() time.Time { return now }
+
+	var first, second RoverState
+	do(t, s, http.MethodPost, "/api/v1/rovers", "", &first)
+	do(t, s, http.MethodPost, "/api/v1/rovers", "", &second)
+	assert.Equal(t, now.Add(time.Minute), first.ExpiresAt)
+
+	// запрос к сессии продлевает её
+	now = now.Add(40 * time.Second)
+	rec := do(t, s, http.MethodGet, "/api/v1/rovers/"+first.ID, "", nil)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	now = now.Add(40 * time.Second)
+	s.expire()
+	rec = do(t, s, http.MethodGet, "/api/v1/rovers/"+first.ID, "", nil)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	rec = do(t, s, http.MethodGet, "/api/v1/rovers/"+second.ID, "", nil)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestServer_RoverSessionConcurrentCommands(t *testing.T) {
+	s := NewServer()
+
+	var created RoverState
+	do(t, s, http.MethodPost, "/api/v1/rovers", "", &created)
+	path := fmt.Sprintf("/api/v1/rovers/%s/commands", created.ID)
+
+	const requests = 50
+	var wg sync.WaitGroup
+	for i := 0; i < requests; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(`{"commands": "FRFRFRFRF"}`))
+			s.ServeHTTP(httptest.NewRecorder(), req)
+		}()
+	}
+	wg.Wait()
+
+	var state RoverState
+	do(t, s, http.MethodGet, "/api/v1/rovers/"+created.ID, "", &state)
+	// каждый запрос возвращает марсоход в исходную клетку и сдвигает на одну клетку на север
+	assert.Equal(t, api.Position{X: 1, Y: 1 + requests}, state.Position)
+	assert.Equal(t, string(models.North), state.Direction)
+	assert.Equal(t, Odometer{Forward: 5 * requests, Turns: 4 * requests}, state.Odometer)
+}